@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApprovedByAnnotation is set on an AccessRequest by the approver to grant the requested access.
+const ApprovedByAnnotation = "hubble.lunar.tech/approved-by"
+
+// AccessRequestSpec defines the role a user asks to be assigned and for how long.
+type AccessRequestSpec struct {
+	// User is the name of a user declared in one of the HubbleRbacs.
+	User string `json:"user"`
+	// Role is the name of a role declared in one of the HubbleRbacs.
+	Role string `json:"role"`
+	// Duration is how long the role is assigned, counted from the time of approval.
+	Duration metav1.Duration `json:"duration"`
+	// Justification explains to the approver why the access is needed.
+	Justification string `json:"justification"`
+}
+
+type AccessRequestPhase string
+
+const (
+	// AccessRequestPending is waiting for an approver to set the approved-by annotation.
+	AccessRequestPending AccessRequestPhase = "Pending"
+	// AccessRequestApproved has been approved but the role has not yet been applied.
+	AccessRequestApproved AccessRequestPhase = "Approved"
+	// AccessRequestGranted has been approved and the role has been applied.
+	AccessRequestGranted AccessRequestPhase = "Granted"
+	// AccessRequestExpired was granted for the requested duration, which has passed.
+	AccessRequestExpired AccessRequestPhase = "Expired"
+	// AccessRequestDenied can not be granted, the message explains why.
+	AccessRequestDenied AccessRequestPhase = "Denied"
+)
+
+// AccessRequestStatus records who approved the request and when the access expires.
+type AccessRequestStatus struct {
+	// +optional
+	Phase AccessRequestPhase `json:"phase,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	ApprovedBy string `json:"approvedBy,omitempty"`
+	// +optional
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+	// ApprovedGeneration is the generation of the spec that was approved. Changing the spec afterwards denies the request.
+	// +optional
+	ApprovedGeneration int64 `json:"approvedGeneration,omitempty"`
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AccessRequest asks for a role to be assigned to a user for a limited time.
+// The role is assigned once an approver has set the approved-by annotation.
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=accessrequests,scope=Namespaced
+// +kubebuilder:printcolumn:name="User",type=string,JSONPath=".spec.user"
+// +kubebuilder:printcolumn:name="Role",type=string,JSONPath=".spec.role"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Approved By",type=string,JSONPath=".status.approvedBy"
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=".status.expiresAt"
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AccessRequestSpec   `json:"spec,omitempty"`
+	Status AccessRequestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AccessRequestList contains a list of AccessRequest
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccessRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AccessRequest{}, &AccessRequestList{})
+}
@@ -0,0 +1,40 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of the given type or nil if it has not been set.
+func (s *HubbleRbacStatus) GetCondition(conditionType ConditionType) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the given type.
+// The transition time is only bumped when the status of the condition changes.
+func (s *HubbleRbacStatus) SetCondition(condition Condition) {
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+
+	existing := s.GetCondition(condition.Type)
+	if existing == nil {
+		s.Conditions = append(s.Conditions, condition)
+		return
+	}
+
+	if existing.Status == condition.Status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
+}
+
+// IsConditionTrue returns true if the condition of the given type has been set to true.
+func (s *HubbleRbacStatus) IsConditionTrue(conditionType ConditionType) bool {
+	condition := s.GetCondition(conditionType)
+	return condition != nil && condition.Status == ConditionTrue
+}
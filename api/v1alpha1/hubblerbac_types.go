@@ -4,6 +4,15 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ApprovedPlanAnnotation is set on a HubbleRbac to the hash of a plan to allow the controller to apply its destructive changes.
+const ApprovedPlanAnnotation = "hubble.lunar.tech/approved-plan"
+
+// OverrideBlastRadiusAnnotation is set on a HubbleRbac to the hash of a plan to allow the controller to apply it even though it removes more than the limits allow.
+const OverrideBlastRadiusAnnotation = "hubble.lunar.tech/override-blast-radius"
+
+// ChangeCauseAnnotation is set on a HubbleRbac to what caused its latest change, e.g. the commit it was deployed from. It is written to the audit log with every change the controller makes.
+const ChangeCauseAnnotation = "hubble.lunar.tech/change-cause"
+
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
@@ -14,23 +23,111 @@ type HubbleRbacSpec struct {
 	Policies     []PolicyReference   `json:"policies"`
 	Databases    []Database          `json:"databases"`
 	DevDatabases []DeveloperDatabase `json:"devDatabases"`
+	// Datalakes declares where the data of the glue databases used in datalakeGrants is stored.
+	// Glue databases that are not declared use the default location of the controller.
+	// +optional
+	Datalakes []Datalake `json:"datalakes,omitempty"`
+	// Teams assign roles to all of their members.
+	// +optional
+	Teams []Team `json:"teams,omitempty"`
+	// Suspend stops the controller from applying any changes while it is true. Nothing is deleted while suspended.
+	// As all HubbleRbacs are applied together, suspending one of them suspends all of them.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// DeletionPolicy decides what happens to the managed resources when the HubbleRbac is deleted.
+	// Retain (the default) leaves them in place, Revoke removes all access granted by the controller.
+	// +kubebuilder:validation:Enum=Retain;Revoke
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
+type DeletionPolicy string
+
+const (
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	DeletionPolicyRevoke DeletionPolicy = "Revoke"
+)
+
 type User struct {
 	Name  string   `json:"name"`
 	Email string   `json:"email"`
 	Roles []string `json:"roles"`
+	// RoleAssignments are roles that are only assigned to the user within a period of time.
+	// +optional
+	RoleAssignments []RoleAssignment `json:"roleAssignments,omitempty"`
+}
+
+// Team assigns its roles to every member. Members are the names of users declared in one of the HubbleRbacs.
+type Team struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+	Roles   []string `json:"roles"`
+}
+
+// RoleAssignment assigns a role to a user from ValidFrom until ExpiresAt. Both are optional.
+type RoleAssignment struct {
+	Role string `json:"role"`
+	// +optional
+	ValidFrom *metav1.Time `json:"validFrom,omitempty"`
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
 }
 
 type Role struct {
-	Name                string   `json:"name"`
+	Name string `json:"name"`
+	// Extends lists roles whose databases, grants and policies are included in this role.
+	// +optional
+	Extends             []string `json:"extends,omitempty"`
 	Databases           []string `json:"databases"`
 	DevDatabases        []string `json:"devDatabases"`
 	DatalakeGrants      []string `json:"datalakeGrants"`
 	DatawarehouseGrants []string `json:"datawarehouseGrants"`
-	Policies            []string `json:"policies"`
+	// Privileges sets the level of access to some of the datawarehouseGrants. The other grants are read only.
+	// +optional
+	Privileges map[string]PrivilegeLevel `json:"privileges,omitempty"`
+	// TableGrants give read access to single tables, or some of their columns, without access to the rest of the schema.
+	// +optional
+	TableGrants []TableGrant `json:"tableGrants,omitempty"`
+	Policies    []string     `json:"policies"`
+	// PolicyStatements give the role extra permissions without declaring an IAM policy.
+	// The statements are rendered into a policy that is managed by the controller and only attached to this role.
+	// +optional
+	PolicyStatements []PolicyStatement `json:"policyStatements,omitempty"`
+	// SessionDuration is the maximum duration of a session of the role, between 1h and 12h.
+	// The default session duration of the controller is used if it is not set. It is not inherited by roles extending the role.
+	// +optional
+	SessionDuration *metav1.Duration `json:"sessionDuration,omitempty"`
+}
+
+// TableGrant gives read access to a table. If columns are given, only those columns can be read.
+type TableGrant struct {
+	Schema string `json:"schema"`
+	Table  string `json:"table"`
+	// +optional
+	Columns []string `json:"columns,omitempty"`
 }
 
+// PolicyStatement is a statement of an IAM policy document.
+type PolicyStatement struct {
+	// +optional
+	Sid string `json:"sid,omitempty"`
+	// +kubebuilder:validation:Enum=Allow;Deny
+	Effect    string   `json:"effect"`
+	Actions   []string `json:"actions"`
+	Resources []string `json:"resources"`
+}
+
+// PrivilegeLevel is the level of access granted on a schema.
+// Read allows querying its tables, write also allows creating and modifying tables, owner also allows referencing its tables from foreign keys.
+// +kubebuilder:validation:Enum=read;write;owner
+type PrivilegeLevel string
+
+const (
+	PrivilegeRead  PrivilegeLevel = "read"
+	PrivilegeWrite PrivilegeLevel = "write"
+	PrivilegeOwner PrivilegeLevel = "owner"
+)
+
 type PolicyReference struct {
 	Name string `json:"name"`
 	Arn  string `json:"arn"`
@@ -41,15 +138,223 @@ type DeveloperDatabase struct {
 	Cluster string `json:"cluster"`
 }
 
+// Datalake is a glue database whose data is stored in S3.
+type Datalake struct {
+	// Name is the name of the glue database, as used in datalakeGrants.
+	Name string `json:"name"`
+	// Location is the S3 location of the data, e.g. s3://datalake/events.
+	// +kubebuilder:validation:Pattern=`^s3://[a-z0-9][a-z0-9.-]{1,61}[a-z0-9](/.*)?$`
+	Location string `json:"location"`
+}
+
 type Database struct {
 	Name     string `json:"name"`
 	Cluster  string `json:"cluster"`
 	Database string `json:"database"`
 }
 
+type ConditionType string
+
+const (
+	// ConditionRedshiftReady is true when the redshift model was applied to every cluster without failed tasks.
+	ConditionRedshiftReady ConditionType = "RedshiftReady"
+	// ConditionIamReady is true when the IAM roles and policies were applied.
+	ConditionIamReady ConditionType = "IamReady"
+	// ConditionGoogleReady is true when the SAML role assignments were applied to the google accounts.
+	ConditionGoogleReady ConditionType = "GoogleReady"
+	// ConditionReady is true when all of the above are true.
+	ConditionReady ConditionType = "Ready"
+	// ConditionInSync is true when the last drift check found no differences between the spec and the managed systems.
+	// It is only set when the controller detects drift instead of applying changes.
+	ConditionInSync ConditionType = "InSync"
+	// ConditionWithinLimits is false when the last plan removes more users, roles or grants than the controller allows in a single apply.
+	// It is only set when the controller has blast radius limits.
+	ConditionWithinLimits ConditionType = "WithinLimits"
+)
+
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition describes the state of one of the subsystems managed by the controller.
+type Condition struct {
+	Type   ConditionType   `json:"type"`
+	Status ConditionStatus `json:"status"`
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastTransitionTime is the last time the status of the condition changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // HubbleRbacStatus defines the observed state of HubbleRbac
 type HubbleRbacStatus struct {
 	Error string `json:"error,omitempty"`
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the spec that the status reflects.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// SuspendedSince is the time the controller stopped applying changes because of spec.suspend or because it is paused.
+	// +optional
+	SuspendedSince *metav1.Time `json:"suspendedSince,omitempty"`
+	// LastAppliedTime is the last time the spec was applied to all subsystems without errors.
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+	// +optional
+	ManagedUsers int `json:"managedUsers,omitempty"`
+	// +optional
+	ManagedRoles int `json:"managedRoles,omitempty"`
+	// +optional
+	ManagedDatabases int `json:"managedDatabases,omitempty"`
+	// UpcomingExpirations lists the active role assignments that will expire, ordered by expiry.
+	// +optional
+	UpcomingExpirations []RoleExpiration `json:"upcomingExpirations,omitempty"`
+	// EffectiveRoles shows the roles that extend other roles with everything they inherit included.
+	// +optional
+	EffectiveRoles []Role `json:"effectiveRoles,omitempty"`
+	// TeamAssignments lists the roles assigned to users through the teams of the HubbleRbac.
+	// +optional
+	TeamAssignments []TeamAssignment `json:"teamAssignments,omitempty"`
+	// Drift is the outcome of the last drift check. It is only set when the controller detects drift instead of applying changes.
+	// +optional
+	Drift *DriftStatus `json:"drift,omitempty"`
+	// Plan lists the changes applying the spec would make. It is only set in dry run mode and while destructive changes await approval.
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+	// LastApply summarizes the operations run by the last apply, including one that failed.
+	// +optional
+	LastApply *ApplyReportStatus `json:"lastApply,omitempty"`
+}
+
+// DriftStatus lists the differences between the spec and the managed systems.
+// Only the first differences of each system are listed, the count includes all of them.
+type DriftStatus struct {
+	// CheckedAt is the time the drift was first found to be as listed, it is not changed by checks that find the same drift.
+	CheckedAt metav1.Time `json:"checkedAt"`
+	Count     int         `json:"count"`
+	// +optional
+	Redshift []string `json:"redshift,omitempty"`
+	// +optional
+	Iam []string `json:"iam,omitempty"`
+	// +optional
+	Google []string `json:"google,omitempty"`
+}
+
+// PlanStatus lists the changes applying the spec would make to each of the managed systems.
+// Only the first changes of each system are listed, the count includes all of them.
+type PlanStatus struct {
+	PlannedAt metav1.Time `json:"plannedAt"`
+	Count     int         `json:"count"`
+	// Hash identifies the plan. Destructive changes are approved by setting the hubble.lunar.tech/approved-plan annotation to it.
+	Hash string `json:"hash"`
+	// Destructive lists the changes that remove users, groups, roles, policies or access.
+	// +optional
+	Destructive []string `json:"destructive,omitempty"`
+	// BlastRadius counts the users, roles and grants the plan removes.
+	BlastRadius BlastRadius `json:"blastRadius"`
+	// +optional
+	Redshift []PlannedRedshiftTask `json:"redshift,omitempty"`
+	// +optional
+	Iam []PlannedIamChange `json:"iam,omitempty"`
+	// +optional
+	Google []PlannedGoogleChange `json:"google,omitempty"`
+}
+
+// BlastRadius counts the users, roles and grants a plan removes.
+type BlastRadius struct {
+	// +optional
+	DroppedUsers int `json:"droppedUsers,omitempty"`
+	// +optional
+	DeletedRoles int `json:"deletedRoles,omitempty"`
+	// +optional
+	RevokedGrants int `json:"revokedGrants,omitempty"`
+	// RemovedPercent is the removed users, roles and grants as a percentage of those managed before the plan is applied.
+	// +optional
+	RemovedPercent int `json:"removedPercent,omitempty"`
+}
+
+// PlannedRedshiftTask is a task that would be run against a redshift cluster, e.g. CreateUser or RevokeAccess.
+type PlannedRedshiftTask struct {
+	Type       string `json:"type"`
+	Identifier string `json:"identifier"`
+	Cluster    string `json:"cluster"`
+	// Database is empty for tasks on users and groups, which belong to the whole cluster.
+	// +optional
+	Database string `json:"database,omitempty"`
+}
+
+// PlannedIamChange is a role or policy that would be created, updated or deleted.
+type PlannedIamChange struct {
+	// Type is one of RoleCreated, RoleUpdated, RoleDeleted, PolicyCreated, PolicyUpdated and PolicyDeleted.
+	Type string `json:"type"`
+	// Name is the name of the role, or the name or ARN of the policy.
+	Name string `json:"name"`
+	// Role is the role the policy is attached to.
+	// +optional
+	Role string `json:"role,omitempty"`
+	// Diff is a unified diff of the policy document, it is only set for the policies managed by the controller.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+}
+
+// PlannedGoogleChange describes how the roles a user can log into through google would be changed.
+type PlannedGoogleChange struct {
+	Email string `json:"email"`
+	// +optional
+	AddedRoles []string `json:"addedRoles,omitempty"`
+	// +optional
+	RemovedRoles []string `json:"removedRoles,omitempty"`
+	// SessionDuration is the new session duration of the user in seconds, it is only set if it changes.
+	// +optional
+	SessionDuration int `json:"sessionDuration,omitempty"`
+}
+
+// ApplyReportStatus counts the operations run against the managed systems by an apply.
+// Only the first failed operations are listed, the count includes all of them.
+type ApplyReportStatus struct {
+	AppliedAt metav1.Time `json:"appliedAt"`
+	Succeeded int         `json:"succeeded"`
+	Failed    int         `json:"failed"`
+	// Skipped counts the redshift tasks that were not run because a task they depend on failed.
+	Skipped int `json:"skipped"`
+	// +optional
+	Failures []FailedOperation `json:"failures,omitempty"`
+}
+
+// FailedOperation is a redshift task, IAM change or google role update that failed.
+type FailedOperation struct {
+	// System is one of redshift, iam and google.
+	System string `json:"system"`
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	// Location is the cluster and database of a redshift task, the role of an IAM change or the email of a google user.
+	// +optional
+	Location string `json:"location,omitempty"`
+	Error    string `json:"error"`
+	// Attempts is the number of times a redshift task was run, including retries.
+	// +optional
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// TeamAssignment records the teams through which a user is assigned a role.
+type TeamAssignment struct {
+	User  string   `json:"user"`
+	Role  string   `json:"role"`
+	Teams []string `json:"teams"`
+}
+
+type RoleExpiration struct {
+	User      string      `json:"user"`
+	Role      string      `json:"role"`
+	ExpiresAt metav1.Time `json:"expiresAt"`
 }
 
 // +kubebuilder:object:root=true
@@ -57,6 +362,11 @@ type HubbleRbacStatus struct {
 // HubbleRbac is the Schema for the hubblerbacs API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=hubblerbacs,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=".status.lastAppliedTime"
+// +kubebuilder:printcolumn:name="Suspended Since",type=date,JSONPath=".status.suspendedSince"
+// +kubebuilder:printcolumn:name="Drift",type=integer,JSONPath=".status.drift.count",priority=1
+// +kubebuilder:printcolumn:name="Planned",type=integer,JSONPath=".status.plan.count",priority=1
 type HubbleRbac struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const accessRequestWebhookPath = "/validate-hubble-lunar-tech-v1alpha1-accessrequest"
+
+// +kubebuilder:webhook:path=/validate-hubble-lunar-tech-v1alpha1-accessrequest,mutating=false,failurePolicy=fail,groups=hubble.lunar.tech,resources=accessrequests,verbs=create;update,versions=v1alpha1,name=vaccessrequest.kb.io
+
+// AccessRequestValidator ensures that an access request can only be approved by the approver named in the approval annotation,
+// and that a request can not be changed once it has been approved.
+type AccessRequestValidator struct {
+	Approvers []string
+	decoder   *admission.Decoder
+}
+
+var _ admission.Handler = &AccessRequestValidator{}
+var _ admission.DecoderInjector = &AccessRequestValidator{}
+
+func (v *AccessRequestValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	request := &hubblev1alpha1.AccessRequest{}
+
+	err := v.decoder.Decode(req, request)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	old := &hubblev1alpha1.AccessRequest{}
+	if req.Operation == admissionv1beta1.Update {
+		err = v.decoder.DecodeRaw(req.OldObject, old)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	reason := v.validate(old, request, req.UserInfo.Username)
+	if reason != "" {
+		return admission.Denied(reason)
+	}
+
+	return admission.Allowed("")
+}
+
+//Returns the reason the change is denied, or an empty string if it is allowed. The old request is empty on creation.
+func (v *AccessRequestValidator) validate(old *hubblev1alpha1.AccessRequest, request *hubblev1alpha1.AccessRequest, username string) string {
+	if request.Spec.Duration.Duration <= 0 {
+		return "spec.duration must be positive"
+	}
+
+	if old.Status.ApprovedAt != nil && !reflect.DeepEqual(old.Spec, request.Spec) {
+		return fmt.Sprintf("the request has been approved by %s and can no longer be changed", old.Status.ApprovedBy)
+	}
+
+	//the controller approves a request with the annotation until the approval is recorded in the status, which users can't write,
+	//so the approver is verified on every change until then, not only when the annotation is set
+	approver := request.Annotations[hubblev1alpha1.ApprovedByAnnotation]
+	if approver == "" || old.Status.ApprovedAt != nil {
+		return ""
+	}
+	if !strings.EqualFold(approver, username) {
+		return fmt.Sprintf("%s can not approve the request on behalf of %s", username, approver)
+	}
+	if !isApprover(v.Approvers, approver) {
+		return fmt.Sprintf("%s is not allowed to approve access requests", approver)
+	}
+	return ""
+}
+
+func (v *AccessRequestValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+func (v *AccessRequestValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(accessRequestWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//An access request that has been given a new status, along with the status it had before.
+type evaluatedAccessRequest struct {
+	*hubblev1alpha1.AccessRequest
+	previous hubblev1alpha1.AccessRequestStatus
+}
+
+func findUser(instance *hubblev1alpha1.HubbleRbac, name string) *hubblev1alpha1.User {
+	for i := range instance.Spec.Users {
+		if instance.Spec.Users[i].Name == name {
+			return &instance.Spec.Users[i]
+		}
+	}
+	return nil
+}
+
+func containsRole(instance *hubblev1alpha1.HubbleRbac, name string) bool {
+	for _, role := range instance.Spec.Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isApprover(approvers []string, name string) bool {
+	for _, approver := range approvers {
+		if strings.EqualFold(approver, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func denied(format string, args ...interface{}) hubblev1alpha1.AccessRequestStatus {
+	return hubblev1alpha1.AccessRequestStatus{Phase: hubblev1alpha1.AccessRequestDenied, Message: fmt.Sprintf(format, args...)}
+}
+
+// evaluateAccessRequest works out the status of an access request against the merged HubbleRbacs.
+// The time of the first valid approval is recorded, so the access expires the requested duration after it was approved.
+// A request that has expired or been denied stays that way, a new request has to be made to get access again.
+// Anyone who can edit the request can set the approval annotation, so it is only trusted when approvalsVerified is set,
+// i.e. when the AccessRequest webhook checks that the annotation is set by the approver it names.
+func evaluateAccessRequest(request *hubblev1alpha1.AccessRequest, merged *hubblev1alpha1.HubbleRbac, approvers []string, approvalsVerified bool, now time.Time) hubblev1alpha1.AccessRequestStatus {
+
+	current := request.Status
+	if current.Phase == hubblev1alpha1.AccessRequestExpired || current.Phase == hubblev1alpha1.AccessRequestDenied {
+		return current
+	}
+
+	user := findUser(merged, request.Spec.User)
+	if user == nil {
+		return denied("no such user: %s", request.Spec.User)
+	}
+	if !containsRole(merged, request.Spec.Role) {
+		return denied("no such role: %s", request.Spec.Role)
+	}
+	if request.Spec.Duration.Duration <= 0 {
+		return denied("the requested duration must be positive")
+	}
+
+	if current.ApprovedAt != nil {
+		if current.ApprovedGeneration != request.Generation {
+			return denied("the request was changed after it was approved by %s", current.ApprovedBy)
+		}
+	} else {
+		approver := request.Annotations[hubblev1alpha1.ApprovedByAnnotation]
+		if approver == "" {
+			return hubblev1alpha1.AccessRequestStatus{Phase: hubblev1alpha1.AccessRequestPending, Message: "waiting for approval"}
+		}
+		if !approvalsVerified {
+			return hubblev1alpha1.AccessRequestStatus{Phase: hubblev1alpha1.AccessRequestPending, Message: "approvals are not accepted while the AccessRequest webhook that verifies the approver is disabled"}
+		}
+		if !isApprover(approvers, approver) {
+			return denied("%s is not allowed to approve access requests", approver)
+		}
+		if strings.EqualFold(approver, user.Email) {
+			return denied("%s can not approve their own access request", approver)
+		}
+
+		approvedAt := metav1.NewTime(now)
+		expiresAt := metav1.NewTime(now.Add(request.Spec.Duration.Duration))
+		current = hubblev1alpha1.AccessRequestStatus{
+			Phase:              hubblev1alpha1.AccessRequestApproved,
+			ApprovedBy:         approver,
+			ApprovedAt:         &approvedAt,
+			ApprovedGeneration: request.Generation,
+			ExpiresAt:          &expiresAt,
+		}
+	}
+
+	if !now.Before(current.ExpiresAt.Time) {
+		current.Phase = hubblev1alpha1.AccessRequestExpired
+		current.Message = ""
+	}
+
+	return current
+}
+
+// assignAccessRequests adds the role of every approved access request to the requesting user of the merged spec,
+// valid from the time of approval until it expires.
+func assignAccessRequests(merged *hubblev1alpha1.HubbleRbac, requests []evaluatedAccessRequest) {
+	for _, request := range requests {
+		phase := request.Status.Phase
+		if phase != hubblev1alpha1.AccessRequestApproved && phase != hubblev1alpha1.AccessRequestGranted {
+			continue
+		}
+		user := findUser(merged, request.Spec.User)
+		if user == nil {
+			continue
+		}
+		//the merged users share their role assignments with the HubbleRbacs they were declared in, so copy before appending
+		assignments := make([]hubblev1alpha1.RoleAssignment, len(user.RoleAssignments), len(user.RoleAssignments)+1)
+		copy(assignments, user.RoleAssignments)
+		user.RoleAssignments = append(assignments, hubblev1alpha1.RoleAssignment{
+			Role:      request.Spec.Role,
+			ValidFrom: request.Status.ApprovedAt,
+			ExpiresAt: request.Status.ExpiresAt,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func accessRequest(approver string) *hubblev1alpha1.AccessRequest {
+	request := &hubblev1alpha1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "datascience", Name: "incident", Generation: 1},
+		Spec: hubblev1alpha1.AccessRequestSpec{
+			User:          "jwr",
+			Role:          "BiAnalyst",
+			Duration:      metav1.Duration{Duration: time.Hour},
+			Justification: "investigating a failed import",
+		},
+	}
+	if approver != "" {
+		request.Annotations = map[string]string{hubblev1alpha1.ApprovedByAnnotation: approver}
+	}
+	return request
+}
+
+func Test_AccessRequest_Approval(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	approvers := []string{"lead@lunar.app", "jwr@lunar.app"}
+	merged := validSpec()
+
+	assert.Equal(hubblev1alpha1.AccessRequestPending, evaluateAccessRequest(accessRequest(""), merged, approvers, true, now).Phase)
+	assert.Equal(hubblev1alpha1.AccessRequestDenied, evaluateAccessRequest(accessRequest("someone@lunar.app"), merged, approvers, true, now).Phase, "only configured approvers can approve")
+	assert.Equal(hubblev1alpha1.AccessRequestDenied, evaluateAccessRequest(accessRequest("jwr@lunar.app"), merged, approvers, true, now).Phase, "users can not approve their own requests")
+
+	request := accessRequest("lead@lunar.app")
+	request.Status = evaluateAccessRequest(request, merged, approvers, true, now)
+
+	assert.Equal(hubblev1alpha1.AccessRequestApproved, request.Status.Phase)
+	assert.Equal("lead@lunar.app", request.Status.ApprovedBy)
+	assert.Equal(now.Add(time.Hour), request.Status.ExpiresAt.Time, "access expires the requested duration after approval")
+
+	later := evaluateAccessRequest(request, merged, approvers, true, now.Add(30*time.Minute))
+	assert.Equal(now, later.ApprovedAt.Time, "the time of approval is kept")
+
+	expired := evaluateAccessRequest(request, merged, approvers, true, now.Add(time.Hour))
+	assert.Equal(hubblev1alpha1.AccessRequestExpired, expired.Phase)
+
+	request.Generation = 2
+	assert.Equal(hubblev1alpha1.AccessRequestDenied, evaluateAccessRequest(request, merged, approvers, true, now).Phase, "changing an approved request denies it")
+
+	unknown := accessRequest("lead@lunar.app")
+	unknown.Spec.Role = "NoSuchRole"
+	assert.Equal(hubblev1alpha1.AccessRequestDenied, evaluateAccessRequest(unknown, merged, approvers, true, now).Phase)
+}
+
+func Test_AccessRequest_NotApprovedWithoutWebhook(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	instance := validSpec()
+	instance.Spec.Users[0].Roles = nil
+	merged, err := mergeHubbleRbacs([]*hubblev1alpha1.HubbleRbac{instance})
+	assert.NoError(err)
+
+	//the requester names an approver in the annotation themselves
+	request := accessRequest("lead@lunar.app")
+	request.Status = evaluateAccessRequest(request, merged, []string{"lead@lunar.app"}, false, now)
+
+	assert.Equal(hubblev1alpha1.AccessRequestPending, request.Status.Phase, "the annotation is not honoured while the webhook is disabled")
+	assert.Nil(request.Status.ApprovedAt)
+	assert.Empty(request.Status.ApprovedBy)
+
+	assignAccessRequests(merged, []evaluatedAccessRequest{{AccessRequest: request}})
+	model, err := buildHubbleModel(merged, now)
+	assert.NoError(err)
+	assert.Empty(model.Users[0].AssignedTo, "the role is not assigned")
+
+	request.Status = evaluateAccessRequest(request, merged, []string{"lead@lunar.app"}, true, now)
+	assert.Equal(hubblev1alpha1.AccessRequestApproved, request.Status.Phase, "the request is approved once the webhook verifies approvals")
+}
+
+func Test_AccessRequest_AssignedUntilExpiry(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	instance := validSpec()
+	instance.Spec.Users[0].Roles = nil
+	merged, err := mergeHubbleRbacs([]*hubblev1alpha1.HubbleRbac{instance})
+	assert.NoError(err)
+
+	request := accessRequest("lead@lunar.app")
+	request.Status = evaluateAccessRequest(request, merged, []string{"lead@lunar.app"}, true, now)
+	pending := accessRequest("")
+	pending.Spec.User = "jwr"
+
+	assignAccessRequests(merged, []evaluatedAccessRequest{{AccessRequest: request}, {AccessRequest: pending}})
+
+	assert.Empty(instance.Spec.Users[0].RoleAssignments, "the HubbleRbac is not modified")
+
+	model, err := buildHubbleModel(merged, now)
+	assert.NoError(err)
+	assert.Len(model.Users[0].AssignedTo, 1, "the approved role is assigned")
+
+	model, err = buildHubbleModel(merged, now.Add(time.Hour))
+	assert.NoError(err)
+	assert.Empty(model.Users[0].AssignedTo, "the role is no longer assigned when the request expires")
+
+	assert.Equal(now.Add(time.Hour), *nextTransition(merged, now))
+}
+
+func Test_AccessRequestValidator(t *testing.T) {
+
+	assert := assert.New(t)
+
+	validator := &AccessRequestValidator{Approvers: []string{"lead@lunar.app"}}
+	empty := &hubblev1alpha1.AccessRequest{}
+
+	assert.Empty(validator.validate(empty, accessRequest(""), "jwr@lunar.app"))
+	assert.NotEmpty(validator.validate(empty, accessRequest("lead@lunar.app"), "jwr@lunar.app"), "approvals can not be made on behalf of others")
+	assert.Empty(validator.validate(accessRequest(""), accessRequest("lead@lunar.app"), "lead@lunar.app"))
+
+	pending := accessRequest("lead@lunar.app")
+	relabelled := accessRequest("lead@lunar.app")
+	relabelled.Labels = map[string]string{"team": "datascience"}
+	assert.NotEmpty(validator.validate(pending, relabelled, "jwr@lunar.app"), "the approver is verified until the approval is recorded, even if the annotation is unchanged")
+	assert.Empty(validator.validate(pending, relabelled, "lead@lunar.app"))
+
+	approved := accessRequest("lead@lunar.app")
+	approved.Status.ApprovedBy = "lead@lunar.app"
+	approved.Status.ApprovedAt = &metav1.Time{Time: time.Now()}
+	changed := accessRequest("lead@lunar.app")
+	changed.Spec.Duration = metav1.Duration{Duration: 24 * time.Hour}
+	assert.NotEmpty(validator.validate(approved, changed, "jwr@lunar.app"), "approved requests can not be changed")
+	assert.Empty(validator.validate(approved, accessRequest("lead@lunar.app"), "jwr@lunar.app"), "other changes are allowed after approval")
+}
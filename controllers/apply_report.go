@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//The number of failed operations listed, so an apply where most tasks fail doesn't exceed the size limit of the status.
+const maxFailedOperations = 20
+
+func applyReportStatus(applyReport *report.ApplyReport, now time.Time) *hubblev1alpha1.ApplyReportStatus {
+	result := &hubblev1alpha1.ApplyReportStatus{
+		AppliedAt: metav1.NewTime(now),
+		Succeeded: applyReport.Count(report.Succeeded),
+		Failed:    applyReport.Count(report.Failed),
+		Skipped:   applyReport.Count(report.Skipped),
+	}
+
+	for _, operation := range applyReport.Failed() {
+		if len(result.Failures) == maxFailedOperations {
+			break
+		}
+		result.Failures = append(result.Failures, hubblev1alpha1.FailedOperation{
+			System:   operation.System,
+			Type:     operation.Type,
+			Target:   operation.Target,
+			Location: operation.Location(),
+			Error:    operation.Error(),
+			Attempts: operation.Attempts,
+		})
+	}
+	return result
+}
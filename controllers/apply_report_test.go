@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ApplyReportStatus(t *testing.T) {
+
+	assert := assert.New(t)
+
+	redshift := report.New()
+	redshift.Add(report.Operation{System: report.Redshift, Type: "CreateGroup", Target: "bianalyst", Cluster: "dev", Outcome: report.Failed, Err: fmt.Errorf("connection reset"), Attempts: 3})
+	redshift.Add(report.Operation{System: report.Redshift, Type: "AddToGroup", Target: "jwr_bianalyst", Cluster: "dev", Outcome: report.Skipped})
+	for i := 0; i < 30; i++ {
+		redshift.Add(report.Operation{System: report.Redshift, Type: "GrantAccess", Target: fmt.Sprintf("group%d", i), Cluster: "dev", Database: "jwr", Outcome: report.Failed, Err: fmt.Errorf("permission denied"), Attempts: 1})
+	}
+	iam := report.New()
+	iam.Add(report.Operation{System: report.Iam, Type: "RoleCreated", Target: "BiAnalyst", Role: "BiAnalyst", Outcome: report.Succeeded})
+
+	result := &service.ApplyResult{
+		Redshift: service.SubsystemResult{Report: redshift},
+		Iam:      service.SubsystemResult{Report: iam},
+		Google:   service.SubsystemResult{Skipped: true},
+	}
+
+	status := applyReportStatus(result.Report(), time.Now())
+
+	assert.Equal(1, status.Succeeded)
+	assert.Equal(31, status.Failed)
+	assert.Equal(1, status.Skipped)
+	assert.Len(status.Failures, maxFailedOperations, "only the first failures are listed")
+	assert.Equal(hubblev1alpha1.FailedOperation{System: "redshift", Type: "CreateGroup", Target: "bianalyst", Location: "dev", Error: "connection reset", Attempts: 3}, status.Failures[0])
+	assert.Equal("dev/jwr", status.Failures[1].Location)
+}
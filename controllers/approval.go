@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+)
+
+//errAwaitingApproval is returned when no changes are applied because the plan contains destructive changes that have not been approved.
+var errAwaitingApproval = fmt.Errorf("destructive changes await approval")
+
+//Returns true if one of the HubbleRbacs has the given annotation set to the hash of the plan.
+func planAnnotated(instances []*hubblev1alpha1.HubbleRbac, annotation string, hash string) bool {
+	for _, instance := range instances {
+		if annotatedHash, ok := instance.Annotations[annotation]; ok && annotatedHash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns true if the HubbleRbac already holds back the plan with the given hash for the given reason.
+//A plan that is held back is planned again on every reconciliation, but its event is only recorded once and the time it was planned is kept.
+func planHeld(instance *hubblev1alpha1.HubbleRbac, hash string, reason string) bool {
+	ready := instance.Status.GetCondition(hubblev1alpha1.ConditionReady)
+	return instance.Status.Plan != nil && instance.Status.Plan.Hash == hash && ready != nil && ready.Reason == reason
+}
+
+//Returns the status of a plan that is held back for the given reason, planned when it was first held back.
+func heldPlanStatus(instance *hubblev1alpha1.HubbleRbac, plan *service.Plan, reason string, now time.Time) *hubblev1alpha1.PlanStatus {
+	status := planStatus(plan, now)
+	if planHeld(instance, status.Hash, reason) {
+		status.PlannedAt = instance.Status.Plan.PlannedAt
+	}
+	return status
+}
+
+func approvalMessage(plan *service.Plan) string {
+	return fmt.Sprintf("the plan contains %d destructive changes, set the %s annotation to %s on a HubbleRbac to apply them", len(plan.Destructive()), hubblev1alpha1.ApprovedPlanAnnotation, plan.Hash())
+}
+
+func (r *HubbleRbacReconciler) setStatusAwaitingApproval(instance *hubblev1alpha1.HubbleRbac, plan *service.Plan, now time.Time, logger logr.Logger) {
+	instance.Status.Error = ""
+	instance.Status.Plan = heldPlanStatus(instance, plan, "AwaitingApproval", now)
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionUnknown, Reason: "AwaitingApproval", Message: approvalMessage(plan)})
+
+	r.updateStatus(instance, logger)
+}
+
+//Holds back the plan if it is destructive and hasn't been approved on one of the given HubbleRbacs.
+func (r *HubbleRbacReconciler) checkApproval(plan *service.Plan, target *hubblev1alpha1.HubbleRbac, instances []*hubblev1alpha1.HubbleRbac, now time.Time) error {
+	destructive := plan.Destructive()
+	if len(destructive) == 0 || planAnnotated(instances, hubblev1alpha1.ApprovedPlanAnnotation, plan.Hash()) {
+		return nil
+	}
+
+	r.Log.Info("destructive changes await approval", "hash", plan.Hash(), "changes", destructive)
+	if !planHeld(target, plan.Hash(), "AwaitingApproval") {
+		r.warning(target, "ApprovalRequired", approvalMessage(plan))
+	}
+	for _, instance := range instances {
+		r.setStatusAwaitingApproval(instance, plan, now, r.Log)
+	}
+	return errAwaitingApproval
+}
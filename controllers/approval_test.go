@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_DestructivePlan(t *testing.T) {
+
+	assert := assert.New(t)
+
+	plan := &service.Plan{
+		Redshift: []redshiftCore.PlannedTask{{Type: redshiftCore.CreateUser, Identifier: "jwr_bianalyst"}, {Type: redshiftCore.AddToGroup, Identifier: "jwr_bianalyst->bianalyst"}},
+		Iam:      []iam.Change{{Type: iam.RoleCreated, Name: "BiAnalyst"}, {Type: iam.PolicyUpdated, Name: "jwr_bianalyst", Role: "BiAnalyst"}},
+		Google:   []google.RoleChange{{Email: "jwr@lunar.app", Added: []string{"BiAnalyst"}}},
+	}
+	assert.Empty(plan.Destructive(), "additive plans are applied without approval")
+
+	hash := plan.Hash()
+
+	plan.Redshift = append(plan.Redshift, redshiftCore.PlannedTask{Type: redshiftCore.DropUser, Identifier: "nra_bianalyst"})
+	plan.Iam = append(plan.Iam, iam.Change{Type: iam.RoleDeleted, Name: "Developer"})
+	plan.Google = append(plan.Google, google.RoleChange{Email: "nra@lunar.app", Removed: []string{"Developer"}})
+
+	assert.Equal([]string{"DropUser(nra_bianalyst)", "RoleDeleted(Developer)", "RolesRemoved(nra@lunar.app)"}, plan.Destructive())
+	assert.NotEqual(hash, plan.Hash(), "the hash changes with the plan")
+	assert.Equal(plan.Hash(), plan.Hash())
+}
+
+func Test_PlanAnnotated(t *testing.T) {
+
+	assert := assert.New(t)
+
+	approved := &hubblev1alpha1.HubbleRbac{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{hubblev1alpha1.ApprovedPlanAnnotation: "0123456789abcdef"}}}
+	other := &hubblev1alpha1.HubbleRbac{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}}
+
+	assert.True(planAnnotated([]*hubblev1alpha1.HubbleRbac{other, approved}, hubblev1alpha1.ApprovedPlanAnnotation, "0123456789abcdef"), "any of the HubbleRbacs can approve the plan")
+	assert.False(planAnnotated([]*hubblev1alpha1.HubbleRbac{other, approved}, hubblev1alpha1.ApprovedPlanAnnotation, "fedcba9876543210"), "the approval is tied to the plan")
+	assert.False(planAnnotated([]*hubblev1alpha1.HubbleRbac{other}, hubblev1alpha1.ApprovedPlanAnnotation, ""), "an empty hash is never approved")
+}
+
+func Test_CheckApproval_HeldPlanIsRecordedOnce(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme), Log: logf.Log, Events: NewHubbleRbacEventSink(fakeRecorder)}
+
+	instance := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	plan := &service.Plan{Redshift: []redshiftCore.PlannedTask{{Type: redshiftCore.DropUser, Identifier: "nra_bianalyst"}}}
+	planned := time.Now()
+
+	assert.Equal(errAwaitingApproval, r.checkApproval(plan, instance, []*hubblev1alpha1.HubbleRbac{instance}, planned))
+	assert.Equal(errAwaitingApproval, r.checkApproval(plan, instance, []*hubblev1alpha1.HubbleRbac{instance}, planned.Add(time.Minute)))
+
+	assert.Len(drain(fakeRecorder.Events), 1, "the event is only recorded when the plan changes")
+	assert.Equal(metav1.NewTime(planned), instance.Status.Plan.PlannedAt)
+
+	plan.Iam = []iam.Change{{Type: iam.RoleDeleted, Name: "Developer"}}
+	assert.Equal(errAwaitingApproval, r.checkApproval(plan, instance, []*hubblev1alpha1.HubbleRbac{instance}, planned.Add(time.Minute)))
+
+	assert.Len(drain(fakeRecorder.Events), 1)
+	assert.Equal(metav1.NewTime(planned.Add(time.Minute)), instance.Status.Plan.PlannedAt)
+}
+
+func Test_CheckBlastRadius_HeldPlanIsRecordedOnce(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme), Log: logf.Log, Events: NewHubbleRbacEventSink(fakeRecorder), Limits: service.BlastRadiusLimits{MaxDroppedUsers: 1}}
+
+	instance := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	plan := &service.Plan{BlastRadius: service.BlastRadius{DroppedUsers: 2}}
+	planned := time.Now()
+
+	assert.Equal(errBlastRadiusExceeded, r.checkBlastRadius(plan, instance, []*hubblev1alpha1.HubbleRbac{instance}, planned))
+	assert.Equal(errBlastRadiusExceeded, r.checkBlastRadius(plan, instance, []*hubblev1alpha1.HubbleRbac{instance}, planned.Add(time.Minute)))
+
+	assert.Len(drain(fakeRecorder.Events), 1, "the event is only recorded when the plan changes")
+	assert.Equal(metav1.NewTime(planned), instance.Status.Plan.PlannedAt)
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"errors"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/audit"
+)
+
+//The changes made while applying the HubbleRbacs are attributed to the one being reconciled, the same way as events are.
+func auditContext(target *hubblev1alpha1.HubbleRbac) audit.Context {
+	return audit.Context{
+		Resource:     qualifiedName(target),
+		Generation:   target.Generation,
+		Cause:        target.Annotations[hubblev1alpha1.ChangeCauseAnnotation],
+		ApprovedPlan: target.Annotations[hubblev1alpha1.ApprovedPlanAnnotation],
+	}
+}
+
+//Returns true if the apply failed because the changes could not be written to the audit log.
+func auditFailed(err error) bool {
+	return errors.Is(err, audit.ErrUnwritten)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/audit"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_AuditContext(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := &hubblev1alpha1.HubbleRbac{ObjectMeta: metav1.ObjectMeta{
+		Namespace:  "datascience",
+		Name:       "hubble",
+		Generation: 12,
+		Annotations: map[string]string{
+			hubblev1alpha1.ChangeCauseAnnotation:  "commit 3f2a1c",
+			hubblev1alpha1.ApprovedPlanAnnotation: "9b1f2c3d4e5f6a7b",
+		},
+	}}
+
+	assert.Equal(audit.Context{Resource: "datascience/hubble", Generation: 12, Cause: "commit 3f2a1c", ApprovedPlan: "9b1f2c3d4e5f6a7b"}, auditContext(instance))
+
+	instance.Annotations = nil
+	assert.Equal(audit.Context{Resource: "datascience/hubble", Generation: 12}, auditContext(instance), "the annotations are optional")
+}
+
+type unavailableSink struct{}
+
+func (s unavailableSink) Write(record audit.Record) error {
+	return fmt.Errorf("connection refused")
+}
+
+func Test_Apply_UnwrittenAuditRecords(t *testing.T) {
+
+	assert := assert.New(t)
+
+	auditLog, err := audit.NewLog(unavailableSink{}, logrtesting.NullLogger{})
+	assert.NoError(err)
+	auditLog.Record(audit.Entry{System: "redshift", Action: "DropUser", Target: "jwr"})
+
+	//the reconciler has no applier, so it would panic if anything was applied
+	r := &HubbleRbacReconciler{Audit: auditLog}
+	result, err := r.apply(hubble.Model{}, nil)
+
+	assert.Nil(result)
+	assert.True(auditFailed(err), "nothing is applied while audit records are waiting to be written")
+	assert.False(auditFailed(fmt.Errorf("access denied")))
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+)
+
+//errBlastRadiusExceeded is returned when no changes are applied because the plan removes more than the limits allow.
+var errBlastRadiusExceeded = fmt.Errorf("the plan removes more than the limits allow")
+
+func blastRadiusMessage(plan *service.Plan, exceeded []string) string {
+	return fmt.Sprintf("%s, set the %s annotation to %s on a HubbleRbac to apply the plan anyway", strings.Join(exceeded, ", "), hubblev1alpha1.OverrideBlastRadiusAnnotation, plan.Hash())
+}
+
+func (r *HubbleRbacReconciler) setStatusBlastRadiusExceeded(instance *hubblev1alpha1.HubbleRbac, plan *service.Plan, message string, now time.Time, logger logr.Logger) {
+	instance.Status.Error = ""
+	instance.Status.Plan = heldPlanStatus(instance, plan, "BlastRadiusExceeded", now)
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionWithinLimits, Status: hubblev1alpha1.ConditionFalse, Reason: "BlastRadiusExceeded", Message: message})
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionFalse, Reason: "BlastRadiusExceeded", Message: message})
+
+	r.updateStatus(instance, logger)
+}
+
+//Refuses the plan if it removes more users, roles or grants than the limits allow, unless it has been overridden on one of the given HubbleRbacs.
+//The WithinLimits condition of plans that are applied is stored along with the outcome of the apply.
+func (r *HubbleRbacReconciler) checkBlastRadius(plan *service.Plan, target *hubblev1alpha1.HubbleRbac, instances []*hubblev1alpha1.HubbleRbac, now time.Time) error {
+	if !r.Limits.Enabled() {
+		return nil
+	}
+
+	exceeded := r.Limits.Exceeded(plan.BlastRadius)
+	if len(exceeded) == 0 {
+		for _, instance := range instances {
+			instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionWithinLimits, Status: hubblev1alpha1.ConditionTrue, Reason: "WithinLimits"})
+		}
+		return nil
+	}
+
+	if planAnnotated(instances, hubblev1alpha1.OverrideBlastRadiusAnnotation, plan.Hash()) {
+		r.Log.Info("applying plan that exceeds the blast radius limits as it has been overridden", "hash", plan.Hash(), "exceeded", exceeded)
+		for _, instance := range instances {
+			instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionWithinLimits, Status: hubblev1alpha1.ConditionTrue, Reason: "Overridden", Message: strings.Join(exceeded, ", ")})
+		}
+		return nil
+	}
+
+	message := blastRadiusMessage(plan, exceeded)
+	r.Log.Info("refusing to apply plan that exceeds the blast radius limits", "hash", plan.Hash(), "exceeded", exceeded)
+	if !planHeld(target, plan.Hash(), "BlastRadiusExceeded") {
+		r.warning(target, "BlastRadiusExceeded", message)
+	}
+	for _, instance := range instances {
+		r.setStatusBlastRadiusExceeded(instance, plan, message, now, r.Log)
+	}
+	return errBlastRadiusExceeded
+}
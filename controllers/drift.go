@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+//The number of differences listed for each system, so a large drift doesn't exceed the size limit of the status.
+const maxDriftItems = 20
+
+func firstDriftItems(items []string) []string {
+	if len(items) > maxDriftItems {
+		return items[:maxDriftItems]
+	}
+	return items
+}
+
+func driftStatus(report *service.DriftReport, now time.Time) *hubblev1alpha1.DriftStatus {
+	return &hubblev1alpha1.DriftStatus{
+		CheckedAt: metav1.NewTime(now),
+		Count:     report.Count(),
+		Redshift:  firstDriftItems(report.Redshift),
+		Iam:       firstDriftItems(report.Iam),
+		Google:    firstDriftItems(report.Google),
+	}
+}
+
+//Returns true if the two drift statuses list the same differences, whenever they were checked.
+func sameDrift(a *hubblev1alpha1.DriftStatus, b *hubblev1alpha1.DriftStatus) bool {
+	return a.Count == b.Count && equality.Semantic.DeepEqual(a.Redshift, b.Redshift) && equality.Semantic.DeepEqual(a.Iam, b.Iam) && equality.Semantic.DeepEqual(a.Google, b.Google)
+}
+
+func driftCondition(report *service.DriftReport) hubblev1alpha1.Condition {
+	if report.Count() == 0 {
+		return hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionInSync, Status: hubblev1alpha1.ConditionTrue, Reason: "NoDrift"}
+	}
+	return hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionInSync, Status: hubblev1alpha1.ConditionFalse, Reason: "DriftDetected", Message: "the managed systems differ from the spec, see status.drift"}
+}
+
+func (r *HubbleRbacReconciler) setStatusDrift(instance *hubblev1alpha1.HubbleRbac, report *service.DriftReport, now time.Time, logger logr.Logger) {
+	instance.Status.Error = ""
+	drift := driftStatus(report, now)
+	if previous := instance.Status.Drift; previous != nil && sameDrift(previous, drift) {
+		drift.CheckedAt = previous.CheckedAt //the time the drift was first found, so a drift that stays the same doesn't change the status
+	}
+	instance.Status.Drift = drift
+	instance.Status.SetCondition(driftCondition(report))
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionUnknown, Reason: "DetectOnly", Message: "changes are not applied when only detecting drift"})
+
+	r.updateStatus(instance, logger)
+}
+
+func (r *HubbleRbacReconciler) setStatusDriftFailed(instance *hubblev1alpha1.HubbleRbac, err error, logger logr.Logger) {
+	instance.Status.Error = err.Error()
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionInSync, Status: hubblev1alpha1.ConditionUnknown, Reason: "DriftDetectionFailed", Message: err.Error()})
+
+	r.updateStatus(instance, logger)
+}
+
+//Reports the differences between the model and the managed systems on the given HubbleRbacs without fixing them.
+func (r *HubbleRbacReconciler) detectDrift(model hubble.Model, instances []*hubblev1alpha1.HubbleRbac, now time.Time) error {
+	report, err := r.Applier.DetectDrift(model)
+	if err != nil {
+		r.Log.Error(err, "unable to detect drift")
+		for _, instance := range instances {
+			r.setStatusDriftFailed(instance, err, r.Log)
+		}
+		return err
+	}
+
+	if report.Count() > 0 {
+		r.Log.Info("drift detected", "redshift", report.Redshift, "iam", report.Iam, "google", report.Google)
+	}
+
+	for _, instance := range instances {
+		r.setStatusDrift(instance, report, now, r.Log)
+	}
+	return nil
+}
+
+//Schedules the next reconciliation no later than the resync interval, so changes made outside of the controller are found.
+func (r *HubbleRbacReconciler) withResync(result ctrl.Result) ctrl.Result {
+	if r.ResyncInterval <= 0 {
+		return result
+	}
+	if result.RequeueAfter == 0 || result.RequeueAfter > r.ResyncInterval {
+		result.RequeueAfter = r.ResyncInterval
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_Resync(t *testing.T) {
+
+	assert := assert.New(t)
+
+	r := &HubbleRbacReconciler{}
+	assert.Equal(ctrl.Result{}, r.withResync(ctrl.Result{}), "resync is disabled by default")
+
+	r.ResyncInterval = time.Hour
+	assert.Equal(ctrl.Result{RequeueAfter: time.Hour}, r.withResync(ctrl.Result{}))
+	assert.Equal(ctrl.Result{RequeueAfter: time.Minute}, r.withResync(ctrl.Result{RequeueAfter: time.Minute}), "an earlier role assignment transition is kept")
+	assert.Equal(ctrl.Result{RequeueAfter: time.Hour}, r.withResync(ctrl.Result{RequeueAfter: 2 * time.Hour}))
+}
+
+func Test_DriftStatus(t *testing.T) {
+
+	assert := assert.New(t)
+
+	report := &service.DriftReport{}
+	assert.Equal(hubblev1alpha1.ConditionTrue, driftCondition(report).Status)
+
+	for i := 0; i < 30; i++ {
+		report.Redshift = append(report.Redshift, fmt.Sprintf("CreateUser(user%d)", i))
+	}
+	report.Iam = []string{"role BiAnalyst does not exist"}
+
+	status := driftStatus(report, time.Now())
+	assert.Equal(31, status.Count)
+	assert.Len(status.Redshift, maxDriftItems, "only the first differences are listed")
+	assert.Equal([]string{"role BiAnalyst does not exist"}, status.Iam)
+	assert.Empty(status.Google)
+	assert.Equal(hubblev1alpha1.ConditionFalse, driftCondition(report).Status)
+
+	instance := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	instance.Status.Drift = status
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme.Scheme), Log: logf.Log}
+
+	r.setStatusDrift(instance, report, time.Now().Add(time.Hour), r.Log)
+	assert.Equal(status.CheckedAt, instance.Status.Drift.CheckedAt, "the time is kept while the drift is the same")
+
+	report.Google = []string{"jwr@lunar.app has no roles"}
+	later := time.Now().Add(time.Hour)
+	r.setStatusDrift(instance, report, later, r.Log)
+	assert.Equal(metav1.NewTime(later), instance.Status.Drift.CheckedAt)
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// HubbleRbacEventSink turns the changes made while applying the HubbleRbacs into kubernetes events.
+// The events are recorded on the HubbleRbac that is being reconciled, as all of them are applied together.
+type HubbleRbacEventSink struct {
+	Recorder record.EventRecorder
+
+	lock   sync.Mutex
+	target runtime.Object
+}
+
+func NewHubbleRbacEventSink(recorder record.EventRecorder) *HubbleRbacEventSink {
+	return &HubbleRbacEventSink{Recorder: recorder}
+}
+
+//Records the events on the given object until it is called again, events are dropped if the object is nil.
+func (s *HubbleRbacEventSink) setTarget(target runtime.Object) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.target = target
+}
+
+func (s *HubbleRbacEventSink) Event(event service.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.target == nil {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if event.Warning {
+		eventType = corev1.EventTypeWarning
+	}
+	s.Recorder.Event(s.target, eventType, event.Reason, event.Message)
+}
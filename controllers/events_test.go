@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+)
+
+func Test_Events(t *testing.T) {
+
+	assert := assert.New(t)
+
+	fakeRecorder := record.NewFakeRecorder(10)
+	sink := NewHubbleRbacEventSink(fakeRecorder)
+	recorder := service.NewEventRecorder(logrtesting.NullLogger{}, sink)
+
+	created := report.Operation{System: report.Iam, Type: iam.RoleCreated.ToString(), Target: "BiAnalyst", Outcome: report.Succeeded}
+
+	applyReport := report.New()
+	applyReport.Add(created)
+	recorder.Reported(applyReport)
+	assert.Empty(fakeRecorder.Events, "events are dropped when no HubbleRbac is being reconciled")
+
+	sink.setTarget(hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{}))
+	applyReport.Add(report.Operation{System: report.Iam, Type: iam.RoleUpdated.ToString(), Target: "BiAnalyst", Outcome: report.Succeeded})
+	applyReport.Add(report.Operation{System: report.Iam, Type: iam.PolicyDeleted.ToString(), Target: "jwr_bianalyst", Outcome: report.Failed, Err: fmt.Errorf("access denied")})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "AddToGroup", Target: "jwr_bianalyst", Outcome: report.Succeeded, Attempts: 1})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "GrantAccess", Target: "bianalyst->lunar(read)", Outcome: report.Succeeded, Attempts: 2})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "AddToGroup", Target: "nra_bianalyst", Outcome: report.Succeeded, Attempts: 1})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "CreateUser", Target: "jwr_bianalyst", Outcome: report.Failed, Err: fmt.Errorf("permission denied"), Attempts: 3})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "AddToGroup", Target: "jwr_bianalyst", Outcome: report.Skipped})
+	applyReport.Add(report.Operation{System: report.Google, Type: "RolesUpdated", Target: "jwr@lunar.app", Detail: "BiAnalyst, DbtDeveloper", Outcome: report.Succeeded})
+	recorder.Reported(applyReport)
+
+	assert.Equal([]string{
+		"Normal RoleCreated IAM role BiAnalyst created",
+		"Normal RoleUpdated IAM role BiAnalyst updated",
+		"Warning TaskFailed redshift task CreateUser(jwr_bianalyst) after 3 attempts failed: permission denied",
+		"Normal GoogleRolesUpdated google roles of jwr@lunar.app set to [BiAnalyst, DbtDeveloper]",
+		"Normal RedshiftTasksSucceeded 3 redshift tasks succeeded: 2 AddToGroup, 1 GrantAccess",
+	}, drain(fakeRecorder.Events), "the succeeded redshift tasks are summed up in one event per report")
+}
+
+func drain(events chan string) []string {
+	var result []string
+	for len(events) > 0 {
+		result = append(result, <-events)
+	}
+	return result
+}
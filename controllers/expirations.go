@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"sort"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+)
+
+// isActive returns true if the assignment grants the role at the given point in time.
+func isActive(assignment hubblev1alpha1.RoleAssignment, now time.Time) bool {
+	if assignment.ValidFrom != nil && now.Before(assignment.ValidFrom.Time) {
+		return false
+	}
+	if assignment.ExpiresAt != nil && !now.Before(assignment.ExpiresAt.Time) {
+		return false
+	}
+	return true
+}
+
+// nextTransition returns the first point in time after now at which a role assignment becomes valid or expires,
+// which is when the model has to be rebuilt and applied again. It returns nil if no such point exists.
+func nextTransition(instance *hubblev1alpha1.HubbleRbac, now time.Time) *time.Time {
+	var result *time.Time
+
+	consider := func(t time.Time) {
+		if t.After(now) && (result == nil || t.Before(*result)) {
+			result = &t
+		}
+	}
+
+	for _, user := range instance.Spec.Users {
+		for _, assignment := range user.RoleAssignments {
+			if assignment.ValidFrom != nil {
+				consider(assignment.ValidFrom.Time)
+			}
+			if assignment.ExpiresAt != nil {
+				consider(assignment.ExpiresAt.Time)
+			}
+		}
+	}
+	return result
+}
+
+// upcomingExpirations lists the role assignments that are currently active and have an expiry, ordered by expiry.
+func upcomingExpirations(instance *hubblev1alpha1.HubbleRbac, now time.Time) []hubblev1alpha1.RoleExpiration {
+	var result []hubblev1alpha1.RoleExpiration
+
+	for _, user := range instance.Spec.Users {
+		for _, assignment := range user.RoleAssignments {
+			if assignment.ExpiresAt != nil && isActive(assignment, now) {
+				result = append(result, hubblev1alpha1.RoleExpiration{
+					User:      user.Name,
+					Role:      assignment.Role,
+					ExpiresAt: *assignment.ExpiresAt,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ExpiresAt.Before(&result[j].ExpiresAt)
+	})
+
+	return result
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func timeRef(t time.Time) *metav1.Time {
+	result := metav1.NewTime(t)
+	return &result
+}
+
+func Test_RoleAssignments_OnlyActiveAssignmentsAreModelled(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	instance := validSpec()
+	instance.Spec.Roles = append(instance.Spec.Roles, hubblev1alpha1.Role{Name: "Incident", Databases: []string{"unstable"}})
+	instance.Spec.Users[0].RoleAssignments = []hubblev1alpha1.RoleAssignment{
+		{Role: "Incident", ExpiresAt: timeRef(now.Add(time.Hour))},
+	}
+	instance.Spec.Users = append(instance.Spec.Users,
+		hubblev1alpha1.User{Name: "nra", Email: "nra@lunar.app", RoleAssignments: []hubblev1alpha1.RoleAssignment{
+			{Role: "Incident", ExpiresAt: timeRef(now.Add(-time.Hour))},
+		}},
+		hubblev1alpha1.User{Name: "kni", Email: "kni@lunar.app", RoleAssignments: []hubblev1alpha1.RoleAssignment{
+			{Role: "Incident", ValidFrom: timeRef(now.Add(2 * time.Hour))},
+		}},
+	)
+
+	model, err := buildHubbleModel(instance, now)
+	assert.NoError(err)
+
+	assert.Len(model.Users[0].AssignedTo, 2, "the active assignment is added to the permanent roles")
+	assert.Empty(model.Users[1].AssignedTo, "expired assignments are dropped")
+	assert.Empty(model.Users[2].AssignedTo, "assignments are not granted before they become valid")
+
+	assert.Equal(now.Add(time.Hour), *nextTransition(instance, now))
+	assert.Equal(now.Add(2*time.Hour), *nextTransition(instance, now.Add(time.Hour)))
+	assert.Nil(nextTransition(instance, now.Add(2*time.Hour)))
+
+	r := &HubbleRbacReconciler{Log: logf.Log, ResyncInterval: 10 * time.Hour}
+	assert.Equal(ctrl.Result{RequeueAfter: time.Hour}, r.withResync(r.requeueAtNextTransition(instance, now)), "the next transition is kept when it is before the resync")
+	assert.Equal(ctrl.Result{RequeueAfter: 10 * time.Hour}, r.withResync(r.requeueAtNextTransition(instance, now.Add(2*time.Hour))))
+}
+
+func Test_RoleAssignments_UpcomingExpirations(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	instance := validSpec()
+	instance.Spec.Users = []hubblev1alpha1.User{
+		{Name: "jwr", Email: "jwr@lunar.app", RoleAssignments: []hubblev1alpha1.RoleAssignment{
+			{Role: "BiAnalyst", ExpiresAt: timeRef(now.Add(2 * time.Hour))},
+		}},
+		{Name: "nra", Email: "nra@lunar.app", RoleAssignments: []hubblev1alpha1.RoleAssignment{
+			{Role: "BiAnalyst", ExpiresAt: timeRef(now.Add(time.Hour))},
+			{Role: "BiAnalyst", ExpiresAt: timeRef(now.Add(-time.Hour))},
+			{Role: "BiAnalyst"},
+		}},
+	}
+
+	expirations := upcomingExpirations(instance, now)
+
+	assert.Len(expirations, 2, "expired and permanent assignments are not listed")
+	assert.Equal("nra", expirations[0].User, "the first assignment to expire is listed first")
+	assert.Equal("jwr", expirations[1].User)
+}
+
+func Test_Validate_RoleAssignments(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Now()
+
+	instance := validSpec()
+	instance.Spec.Users[0].RoleAssignments = []hubblev1alpha1.RoleAssignment{
+		{Role: "NoSuchRole", ExpiresAt: timeRef(now)},
+		{Role: "BiAnalyst", ValidFrom: timeRef(now), ExpiresAt: timeRef(now.Add(-time.Hour))},
+	}
+
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+
+	assert.Contains(fields, "spec.users[0].roleAssignments[0].role", "missing role is reported")
+	assert.Contains(fields, "spec.users[0].roleAssignments[1].expiresAt", "expiry before validFrom is reported")
+}
@@ -18,20 +18,46 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/go-logr/logr"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/audit"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
 )
 
 var log = logf.Log.WithName("controller_hubblerbac")
 
+//errSuspended is returned when no changes are applied because reconciliation is suspended.
+var errSuspended = fmt.Errorf("reconciliation is suspended")
+
+//errInvalidSpec is returned when the HubbleRbacs cannot be applied until they have been changed, so there is no point in retrying.
+var errInvalidSpec = fmt.Errorf("invalid HubbleRbac spec")
+
+//The finalizer ensures that the deletion policy is carried out before the HubbleRbac is removed
+const finalizerName = "hubble.lunar.tech/finalizer"
+
 // HubbleRbacReconciler reconciles a HubbleRbac object
 type HubbleRbacReconciler struct {
 	client.Client
@@ -39,13 +65,81 @@ type HubbleRbacReconciler struct {
 	Scheme  *runtime.Scheme
 	Applier *service.Applier
 	DryRun  bool
+	//Paused stops all HubbleRbacs from being applied, as if they were all suspended
+	Paused bool
+	//Approvers are the users that may approve access requests
+	Approvers []string
+	//ApprovalsVerified is set when the AccessRequest webhook is registered. Access requests are not approved without it, as the approval annotation could be set by anyone
+	ApprovalsVerified bool
+	//ResyncInterval reapplies the HubbleRbacs periodically to undo changes made outside of the controller, zero disables it
+	ResyncInterval time.Duration
+	//DetectOnly reports the differences between the HubbleRbacs and the managed systems instead of applying them
+	DetectOnly bool
+	//RequireApproval holds back plans with destructive changes until they are approved with the hubble.lunar.tech/approved-plan annotation
+	RequireApproval bool
+	//Limits refuse to apply plans that remove too many users, roles or grants at once
+	Limits service.BlastRadiusLimits
+	//Events records the changes made by the applier as events on the HubbleRbac being reconciled, it is optional
+	Events *HubbleRbacEventSink
+	//Audit records every change made by the applier along with the HubbleRbac being reconciled, it is optional
+	Audit *audit.Log
+
+	applyLock sync.Mutex
 }
 
 // +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubblerbacs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubblerbacs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=accessrequests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=accessrequests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+func subsystemCondition(conditionType hubblev1alpha1.ConditionType, result service.SubsystemResult, dryRun bool) hubblev1alpha1.Condition {
+	switch {
+	case result.Err != nil:
+		return hubblev1alpha1.Condition{Type: conditionType, Status: hubblev1alpha1.ConditionFalse, Reason: "ApplyFailed", Message: result.Err.Error()}
+	case result.Skipped && dryRun:
+		return hubblev1alpha1.Condition{Type: conditionType, Status: hubblev1alpha1.ConditionUnknown, Reason: "DryRun", Message: "changes are not applied in dry run mode"}
+	case result.Skipped:
+		return hubblev1alpha1.Condition{Type: conditionType, Status: hubblev1alpha1.ConditionUnknown, Reason: "Skipped", Message: "not applied because a previous step failed"}
+	default:
+		return hubblev1alpha1.Condition{Type: conditionType, Status: hubblev1alpha1.ConditionTrue, Reason: "Applied"}
+	}
+}
+
+//Returns a copy of the status without the times that are set on every reconciliation, so two statuses only differ if something else changed.
+func withoutTimestamps(status hubblev1alpha1.HubbleRbacStatus) *hubblev1alpha1.HubbleRbacStatus {
+	result := status.DeepCopy()
+	if result.LastAppliedTime != nil {
+		result.LastAppliedTime = &metav1.Time{}
+	}
+	if result.LastApply != nil {
+		result.LastApply.AppliedAt = metav1.Time{}
+	}
+	if result.Plan != nil {
+		result.Plan.PlannedAt = metav1.Time{}
+	}
+	if result.Drift != nil {
+		result.Drift.CheckedAt = metav1.Time{}
+	}
+	return result
+}
+
+//Stores the status unless only its timestamps have changed, as every stored status would otherwise cause another reconciliation.
+func (r *HubbleRbacReconciler) updateStatus(instance *hubblev1alpha1.HubbleRbac, logger logr.Logger) {
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.Status.UpcomingExpirations = upcomingExpirations(instance, time.Now())
+
+	for i := range instance.Status.Conditions {
+		instance.Status.Conditions[i].ObservedGeneration = instance.Generation
+	}
+
+	stored := &hubblev1alpha1.HubbleRbac{}
+	err := r.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}, stored)
+	if err == nil && equality.Semantic.DeepEqual(withoutTimestamps(stored.Status), withoutTimestamps(instance.Status)) {
+		return
+	}
 
-func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1alpha1.HubbleRbac, err error, logger logr.Logger) {
-	instance.Status.Error = err.Error()
 	statusUpdateError := r.Status().Update(context.TODO(), instance)
 
 	if statusUpdateError != nil {
@@ -53,13 +147,89 @@ func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1alpha1.HubbleRb
 	}
 }
 
-func (r *HubbleRbacReconciler) setStatusOk(instance *hubblev1alpha1.HubbleRbac, logger logr.Logger) {
+func (r *HubbleRbacReconciler) setSubsystemConditions(instance *hubblev1alpha1.HubbleRbac, result *service.ApplyResult) {
+	instance.Status.SetCondition(subsystemCondition(hubblev1alpha1.ConditionRedshiftReady, result.Redshift, r.DryRun))
+	instance.Status.SetCondition(subsystemCondition(hubblev1alpha1.ConditionIamReady, result.Iam, r.DryRun))
+	instance.Status.SetCondition(subsystemCondition(hubblev1alpha1.ConditionGoogleReady, result.Google, r.DryRun))
+	instance.Status.ManagedUsers = result.ManagedUsers
+	instance.Status.ManagedRoles = result.ManagedRoles
+	instance.Status.ManagedDatabases = result.ManagedDatabases
+	if !r.DryRun {
+		instance.Status.LastApply = applyReportStatus(result.Report(), time.Now())
+	}
+}
+
+//The result is nil if the spec could not be turned into a hubble model, in which case the subsystem conditions are left untouched.
+func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1alpha1.HubbleRbac, result *service.ApplyResult, err error, logger logr.Logger) {
+	instance.Status.Error = err.Error()
+
+	reason := "InvalidSpec"
+	if result != nil {
+		reason = "ApplyFailed"
+		r.setSubsystemConditions(instance, result)
+	}
+	if auditFailed(err) {
+		reason = "AuditLogFailed"
+	}
+
+	instance.Status.SetCondition(hubblev1alpha1.Condition{
+		Type:    hubblev1alpha1.ConditionReady,
+		Status:  hubblev1alpha1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+
+	r.updateStatus(instance, logger)
+}
+
+func (r *HubbleRbacReconciler) setStatusOk(instance *hubblev1alpha1.HubbleRbac, result *service.ApplyResult, logger logr.Logger) {
 	instance.Status.Error = ""
-	statusUpdateError := r.Status().Update(context.TODO(), instance)
+	r.setSubsystemConditions(instance, result)
 
-	if statusUpdateError != nil {
-		logger.Error(statusUpdateError, "unable to update status")
+	instance.Status.Plan = nil
+	if result.Plan != nil {
+		instance.Status.Plan = planStatus(result.Plan, time.Now())
+	}
+
+	if result.Succeeded() {
+		now := metav1.Now()
+		instance.Status.LastAppliedTime = &now
+		instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionTrue, Reason: "Applied"})
+	} else {
+		instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionUnknown, Reason: "DryRun", Message: "changes are not applied in dry run mode, see status.plan"})
+	}
+
+	r.updateStatus(instance, logger)
+}
+
+//Marks the instance as suspended, keeping the time it was first suspended.
+func (r *HubbleRbacReconciler) setStatusSuspended(instance *hubblev1alpha1.HubbleRbac, reason string, logger logr.Logger) {
+	if instance.Status.SuspendedSince == nil {
+		now := metav1.Now()
+		instance.Status.SuspendedSince = &now
+	}
+
+	instance.Status.SetCondition(hubblev1alpha1.Condition{
+		Type:    hubblev1alpha1.ConditionReady,
+		Status:  hubblev1alpha1.ConditionUnknown,
+		Reason:  "Suspended",
+		Message: reason,
+	})
+
+	r.updateStatus(instance, logger)
+}
+
+//Returns why reconciliation is suspended, or an empty string if it isn't.
+func (r *HubbleRbacReconciler) suspendedBy(instances []*hubblev1alpha1.HubbleRbac) string {
+	if r.Paused {
+		return "the controller is paused"
 	}
+	for _, instance := range instances {
+		if instance.Spec.Suspend {
+			return fmt.Sprintf("suspended by %s", qualifiedName(instance))
+		}
+	}
+	return ""
 }
 
 func (r *HubbleRbacReconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
@@ -77,26 +247,341 @@ func (r *HubbleRbacReconciler) Reconcile(request ctrl.Request) (ctrl.Result, err
 		return reconcile.Result{}, err
 	}
 
-	model, err := buildHubbleModel(instance)
+	//all HubbleRbacs contribute to the same desired state, so only one of them may be applied at a time
+	r.applyLock.Lock()
+	defer r.applyLock.Unlock()
+
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(instance)
+	}
+
+	if !controllerutil.ContainsFinalizer(instance, finalizerName) {
+		controllerutil.AddFinalizer(instance, finalizerName)
+		err = r.Update(context.TODO(), instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	err = r.forgetRetained(instance)
 	if err != nil {
-		r.Log.Error(err, "invalid HubbleRbac CR encountered")
-		r.setStatusFailed(instance, err, r.Log)
-		return reconcile.Result{}, nil //don't reschedule, if we can't construct the hubble model from the CR it is a permanent problem
+		return reconcile.Result{}, err
+	}
+
+	result, err := r.applyAll(instance, nil)
+	if err == errInvalidSpec {
+		return ctrl.Result{}, nil //don't reschedule, if we can't construct the hubble model from the CRs it is a permanent problem
+	}
+	if err == errSuspended {
+		return ctrl.Result{}, nil //resuming changes the spec of a HubbleRbac or restarts the controller, which triggers a new reconciliation
+	}
+	if err == errAwaitingApproval || err == errBlastRadiusExceeded {
+		return r.withResync(result), nil //approving or overriding changes the annotations of a HubbleRbac, which triggers a new reconciliation, expiries are requeued until then
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return r.withResync(result), nil
+}
+
+func (r *HubbleRbacReconciler) reconcileDelete(instance *hubblev1alpha1.HubbleRbac) (ctrl.Result, error) {
+
+	if !controllerutil.ContainsFinalizer(instance, finalizerName) {
+		return reconcile.Result{}, nil
 	}
 
-	err = r.Applier.Apply(model, r.DryRun)
+	if instance.Spec.DeletionPolicy == hubblev1alpha1.DeletionPolicyRevoke && r.DetectOnly {
+		r.Log.Info("access will be revoked when the controller applies changes again", "name", instance.Name)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if instance.Spec.DeletionPolicy == hubblev1alpha1.DeletionPolicyRevoke {
+		r.Log.Info("revoking all access granted by HubbleRbac before it is deleted", "name", instance.Name)
+
+		//the spec retained from an earlier HubbleRbac with the same name would otherwise keep the access in place
+		err := r.forgetRetained(instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		_, err = r.applyAll(nil, instance)
+		if err == errInvalidSpec {
+			return reconcile.Result{}, fmt.Errorf("unable to revoke access granted by %s because the remaining HubbleRbacs are invalid", qualifiedName(instance))
+		}
+		if err == errSuspended {
+			r.Log.Info("access will be revoked when reconciliation is resumed", "name", instance.Name)
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		if err == errAwaitingApproval || err == errBlastRadiusExceeded {
+			r.Log.Info("access will be revoked when the plan has been approved", "name", instance.Name)
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		if err != nil {
+			return reconcile.Result{}, err //keep the finalizer and retry until access has been revoked
+		}
+	} else {
+		//the HubbleRbac is no longer listed once it is gone, so its spec is kept to leave its resources in place when the others are applied
+		err := r.retain(instance)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(instance, finalizerName)
+	err := r.Update(context.TODO(), instance)
 	if err != nil {
-		r.setStatusFailed(instance, err, r.Log)
 		return reconcile.Result{}, err
 	}
 
-	r.setStatusOk(instance, r.Log)
+	return reconcile.Result{}, nil
+}
+
+//Lists the HubbleRbacs that are not being deleted. The given instance replaces its (possibly stale) cached copy.
+func (r *HubbleRbacReconciler) listActive(current *hubblev1alpha1.HubbleRbac) ([]*hubblev1alpha1.HubbleRbac, error) {
+	list := &hubblev1alpha1.HubbleRbacList{}
+
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*hubblev1alpha1.HubbleRbac
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if current != nil && instance.Namespace == current.Namespace && instance.Name == current.Name {
+			instance = current
+		}
+		if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+			result = append(result, instance)
+		}
+	}
+	return result, nil
+}
+
+//Evaluates all access requests against the merged HubbleRbacs. The new status is set on the requests but not stored.
+func (r *HubbleRbacReconciler) evaluateAccessRequests(merged *hubblev1alpha1.HubbleRbac, now time.Time) ([]evaluatedAccessRequest, error) {
+	list := &hubblev1alpha1.AccessRequestList{}
+
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []evaluatedAccessRequest
+	for i := range list.Items {
+		request := &list.Items[i]
+		previous := request.Status
+		request.Status = evaluateAccessRequest(request, merged, r.Approvers, r.ApprovalsVerified, now)
+		result = append(result, evaluatedAccessRequest{AccessRequest: request, previous: previous})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace+"/"+result[i].Name < result[j].Namespace+"/"+result[j].Name
+	})
+	return result, nil
+}
+
+func (r *HubbleRbacReconciler) updateAccessRequestStatus(requests []evaluatedAccessRequest, applied bool) {
+	for _, request := range requests {
+		if applied && request.Status.Phase == hubblev1alpha1.AccessRequestApproved {
+			request.Status.Phase = hubblev1alpha1.AccessRequestGranted
+			r.Log.Info("access request granted", "namespace", request.Namespace, "name", request.Name, "user", request.Spec.User, "role", request.Spec.Role, "approvedBy", request.Status.ApprovedBy)
+		}
+		if reflect.DeepEqual(request.previous, request.Status) {
+			continue
+		}
+		err := r.Status().Update(context.TODO(), request.AccessRequest)
+		if err != nil {
+			r.Log.Error(err, "unable to update access request status", "namespace", request.Namespace, "name", request.Name)
+		}
+	}
+}
+
+//Merges all active and retained HubbleRbacs into one model, optionally revoking the access granted by a deleted HubbleRbac, and applies it.
+//The result requeues the reconciliation when the next role assignment becomes valid or expires.
+func (r *HubbleRbacReconciler) applyAll(current *hubblev1alpha1.HubbleRbac, revoked *hubblev1alpha1.HubbleRbac) (ctrl.Result, error) {
+
+	instances, err := r.listActive(current)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	affected := instances
+	if revoked != nil {
+		affected = append(affected, revoked)
+	}
+
+	if reason := r.suspendedBy(instances); reason != "" {
+		r.Log.Info("not applying any changes", "reason", reason)
+		for _, instance := range instances {
+			r.setStatusSuspended(instance, reason, r.Log)
+		}
+		return ctrl.Result{}, errSuspended
+	}
+	for _, instance := range instances {
+		instance.Status.SuspendedSince = nil //stored along with the outcome of applying the HubbleRbacs below
+	}
+
+	merged, err := mergeHubbleRbacs(instances)
+	if err != nil {
+		r.Log.Error(err, "conflicting HubbleRbac CRs encountered")
+		for _, instance := range affected {
+			r.setStatusFailed(instance, nil, err, r.Log)
+		}
+		return ctrl.Result{}, errInvalidSpec
+	}
+
+	retained, err := r.listRetained(instances)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	mergeRetained(merged, retained)
+
+	now := time.Now()
+	requests, err := r.evaluateAccessRequests(merged, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	assignAccessRequests(merged, requests)
+
+	model, err := buildHubbleModel(merged, now)
+	if err != nil {
+		r.Log.Error(err, "invalid HubbleRbac CR encountered")
+		for _, instance := range affected {
+			r.setStatusFailed(instance, nil, err, r.Log)
+		}
+		return ctrl.Result{}, errInvalidSpec
+	}
 
-	return ctrl.Result{}, nil
+	flattened, err := flattenRoles(merged.Spec.Roles)
+	if err != nil {
+		return ctrl.Result{}, err //buildHubbleModel has already flattened the roles, so this can't happen
+	}
+	for _, instance := range instances {
+		instance.Status.EffectiveRoles = effectiveRoles(instance, flattened)
+
+		assignments := teamAssignments(instance)
+		logTeamAssignmentChanges(r.Log, instance, instance.Status.TeamAssignments, assignments)
+		instance.Status.TeamAssignments = assignments
+	}
+
+	if revoked != nil {
+		revokeAccess(&model, revoked)
+	}
+
+	if r.DetectOnly {
+		err = r.detectDrift(model, instances, now)
+		r.updateAccessRequestStatus(requests, false)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		target := current
+		if target == nil {
+			target = revoked
+		}
+
+		var plan *service.Plan
+		if r.planBeforeApply() {
+			plan, err = r.checkPlan(model, target, affected, now)
+			if err != nil {
+				r.updateAccessRequestStatus(requests, false)
+				return r.requeueAtNextTransition(merged, now), err //assignments that expire while the plan is held back are revoked once it is applied
+			}
+		}
+
+		r.setEventTarget(target)
+		r.Audit.SetContext(auditContext(target))
+		result, err := r.apply(model, plan)
+		r.Audit.SetContext(audit.Context{})
+		r.setEventTarget(nil)
+
+		if err != nil {
+			r.warning(target, "ApplyFailed", err.Error())
+			for _, instance := range affected {
+				r.setStatusFailed(instance, result, err, r.Log)
+			}
+			r.updateAccessRequestStatus(requests, false)
+			return ctrl.Result{}, err
+		}
+
+		for _, instance := range instances {
+			r.setStatusOk(instance, result, r.Log)
+		}
+		r.updateAccessRequestStatus(requests, result.Succeeded())
+	}
+
+	return r.requeueAtNextTransition(merged, now), nil
+}
+
+//Requeues the reconciliation when the next role assignment becomes valid or expires.
+func (r *HubbleRbacReconciler) requeueAtNextTransition(merged *hubblev1alpha1.HubbleRbac, now time.Time) ctrl.Result {
+	next := nextTransition(merged, now)
+	if next == nil {
+		return ctrl.Result{}
+	}
+	r.Log.Info("role assignments change at a later point in time, scheduling reconciliation", "at", next)
+
+	return ctrl.Result{RequeueAfter: next.Sub(now)}
+}
+
+func (r *HubbleRbacReconciler) setEventTarget(target *hubblev1alpha1.HubbleRbac) {
+	if r.Events == nil {
+		return
+	}
+	if target == nil {
+		r.Events.setTarget(nil)
+	} else {
+		r.Events.setTarget(target)
+	}
+}
+
+func (r *HubbleRbacReconciler) warning(target *hubblev1alpha1.HubbleRbac, reason string, message string) {
+	if r.Events != nil {
+		r.Events.Recorder.Event(target, corev1.EventTypeWarning, reason, message)
+	}
 }
 
 func (r *HubbleRbacReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&hubblev1alpha1.HubbleRbac{}).
+		For(&hubblev1alpha1.HubbleRbac{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, annotationsChanged))).
+		Watches(&source.Kind{Type: &hubblev1alpha1.AccessRequest{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.mapAccessRequest)}, builder.WithPredicates(accessRequestChanged)).
 		Complete(r)
 }
+
+//Every reconciliation applies all HubbleRbacs, so any one of them can be reconciled when an access request changes.
+func (r *HubbleRbacReconciler) mapAccessRequest(_ handler.MapObject) []reconcile.Request {
+	list := &hubblev1alpha1.HubbleRbacList{}
+
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "unable to list HubbleRbacs for access request")
+		return nil
+	}
+	if len(list.Items) == 0 {
+		return nil
+	}
+
+	first := list.Items[0]
+	for _, instance := range list.Items[1:] {
+		if qualifiedName(&instance) < qualifiedName(&first) {
+			first = instance
+		}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: first.Namespace, Name: first.Name}}}
+}
+
+//The controller updates the status of the HubbleRbacs, so only changes to the spec, deletion and changes to the annotations, e.g. an approved plan, should trigger a reconciliation.
+var annotationsChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+	},
+}
+
+//The controller updates the status of access requests, so only changes to the spec and to the approval should trigger a reconciliation.
+var accessRequestChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+			e.MetaOld.GetAnnotations()[hubblev1alpha1.ApprovedByAnnotation] != e.MetaNew.GetAnnotations()[hubblev1alpha1.ApprovedByAnnotation]
+	},
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const validatingWebhookPath = "/validate-hubble-lunar-tech-v1alpha1-hubblerbac"
+
+// +kubebuilder:webhook:path=/validate-hubble-lunar-tech-v1alpha1-hubblerbac,mutating=false,failurePolicy=fail,groups=hubble.lunar.tech,resources=hubblerbacs,verbs=create;update,versions=v1alpha1,name=vhubblerbac.kb.io
+
+// HubbleRbacValidator rejects HubbleRbac specs that the controller would not be able to apply.
+// Specs are validated together with the other HubbleRbacs in the cluster, as they are merged before being applied.
+type HubbleRbacValidator struct {
+	client.Client
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &HubbleRbacValidator{}
+var _ admission.DecoderInjector = &HubbleRbacValidator{}
+
+func (v *HubbleRbacValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &hubblev1alpha1.HubbleRbac{}
+
+	err := v.decoder.Decode(req, instance)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var old *hubblev1alpha1.HubbleRbac
+	if req.Operation == admissionv1beta1.Update {
+		old = &hubblev1alpha1.HubbleRbac{}
+		err = v.decoder.DecodeRaw(req.OldObject, old)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if !specChanged(old, instance) {
+		return admission.Allowed("")
+	}
+
+	list := &hubblev1alpha1.HubbleRbacList{}
+	err = v.List(ctx, list)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	var others []*hubblev1alpha1.HubbleRbac
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Namespace == req.Namespace && other.Name == req.Name {
+			continue
+		}
+		if other.ObjectMeta.DeletionTimestamp.IsZero() {
+			others = append(others, other)
+		}
+	}
+
+	errs := validateHubbleRbac(instance, others)
+	if len(errs) > 0 {
+		invalid := apierrors.NewInvalid(hubblev1alpha1.GroupVersion.WithKind("HubbleRbac").GroupKind(), instance.Name, errs)
+		return admission.Denied(invalid.Error())
+	}
+
+	return admission.Allowed("")
+}
+
+//Returns false for changes that don't need to be validated: a HubbleRbac that is being deleted, e.g. when its finalizer is removed,
+//and updates of the metadata only, which must be allowed even if the spec conflicts with a HubbleRbac created since. The old HubbleRbac is nil on creation.
+func specChanged(old *hubblev1alpha1.HubbleRbac, instance *hubblev1alpha1.HubbleRbac) bool {
+	if !instance.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return old == nil || !reflect.DeepEqual(old.Spec, instance.Spec)
+}
+
+func (v *HubbleRbacValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+func (v *HubbleRbacValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(validatingWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+)
+
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+var privilegeRanks = map[hubblev1alpha1.PrivilegeLevel]int{
+	hubblev1alpha1.PrivilegeRead:  1,
+	hubblev1alpha1.PrivilegeWrite: 2,
+	hubblev1alpha1.PrivilegeOwner: 3,
+}
+
+//Adds the privileges to the existing ones, keeping the highest level if a schema is present in both.
+func mergePrivileges(existing map[string]hubblev1alpha1.PrivilegeLevel, privileges map[string]hubblev1alpha1.PrivilegeLevel) map[string]hubblev1alpha1.PrivilegeLevel {
+	for schema, level := range privileges {
+		if existing == nil {
+			existing = make(map[string]hubblev1alpha1.PrivilegeLevel)
+		}
+		if privilegeRanks[level] > privilegeRanks[existing[schema]] {
+			existing[schema] = level
+		}
+	}
+	return existing
+}
+
+//Adds the table grants to the existing ones. If a table is granted more than once, the grant includes the columns of both,
+//and a grant without columns gives access to the whole table.
+func mergeTableGrants(existing []hubblev1alpha1.TableGrant, grants []hubblev1alpha1.TableGrant) []hubblev1alpha1.TableGrant {
+	for _, grant := range grants {
+		found := false
+		for i := range existing {
+			if existing[i].Schema != grant.Schema || existing[i].Table != grant.Table {
+				continue
+			}
+			found = true
+			if len(existing[i].Columns) == 0 || len(grant.Columns) == 0 {
+				existing[i].Columns = nil
+			} else {
+				existing[i].Columns = appendMissing(existing[i].Columns, grant.Columns...)
+			}
+		}
+		if !found {
+			existing = append(existing, hubblev1alpha1.TableGrant{Schema: grant.Schema, Table: grant.Table, Columns: appendMissing(nil, grant.Columns...)})
+		}
+	}
+	return existing
+}
+
+//Adds the policy statements that are not already in the existing ones.
+func mergePolicyStatements(existing []hubblev1alpha1.PolicyStatement, statements []hubblev1alpha1.PolicyStatement) []hubblev1alpha1.PolicyStatement {
+	for _, statement := range statements {
+		found := false
+		for _, e := range existing {
+			if reflect.DeepEqual(e, statement) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, statement)
+		}
+	}
+	return existing
+}
+
+type roleFlattener struct {
+	declared map[string]hubblev1alpha1.Role
+	resolved map[string]hubblev1alpha1.Role
+	visiting map[string]bool
+}
+
+func (f *roleFlattener) flatten(name string, path []string) (hubblev1alpha1.Role, error) {
+	if role, ok := f.resolved[name]; ok {
+		return role, nil
+	}
+	role, ok := f.declared[name]
+	if !ok {
+		return role, fmt.Errorf("no such role: %s", name)
+	}
+	path = append(path, name)
+	if f.visiting[name] {
+		return role, fmt.Errorf("role %s extends itself: %s", name, strings.Join(path, " -> "))
+	}
+	f.visiting[name] = true
+
+	effective := hubblev1alpha1.Role{
+		Name:                role.Name,
+		Databases:           appendMissing(nil, role.Databases...),
+		DevDatabases:        appendMissing(nil, role.DevDatabases...),
+		DatalakeGrants:      appendMissing(nil, role.DatalakeGrants...),
+		DatawarehouseGrants: appendMissing(nil, role.DatawarehouseGrants...),
+		Policies:            appendMissing(nil, role.Policies...),
+		Privileges:          mergePrivileges(nil, role.Privileges),
+		TableGrants:         mergeTableGrants(nil, role.TableGrants),
+		PolicyStatements:    mergePolicyStatements(nil, role.PolicyStatements),
+		SessionDuration:     role.SessionDuration,
+	}
+
+	for _, parentName := range role.Extends {
+		parent, err := f.flatten(parentName, path)
+		if err != nil {
+			return role, err
+		}
+		effective.Databases = appendMissing(effective.Databases, parent.Databases...)
+		effective.DevDatabases = appendMissing(effective.DevDatabases, parent.DevDatabases...)
+		effective.DatalakeGrants = appendMissing(effective.DatalakeGrants, parent.DatalakeGrants...)
+		effective.DatawarehouseGrants = appendMissing(effective.DatawarehouseGrants, parent.DatawarehouseGrants...)
+		effective.Policies = appendMissing(effective.Policies, parent.Policies...)
+		effective.Privileges = mergePrivileges(effective.Privileges, parent.Privileges)
+		effective.TableGrants = mergeTableGrants(effective.TableGrants, parent.TableGrants)
+		effective.PolicyStatements = mergePolicyStatements(effective.PolicyStatements, parent.PolicyStatements)
+	}
+
+	delete(f.visiting, name)
+	f.resolved[name] = effective
+	return effective, nil
+}
+
+// flattenRoles resolves the roles that every role extends, directly or indirectly, into one effective role per role.
+// The effective role has the union of the databases, grants and policies of the role and everything it extends,
+// with the highest privilege level on schemas that are granted more than once and the columns of tables that are granted more than once.
+// It fails if a role extends a role that does not exist, or if a role ends up extending itself.
+func flattenRoles(roles []hubblev1alpha1.Role) (map[string]hubblev1alpha1.Role, error) {
+	f := &roleFlattener{
+		declared: make(map[string]hubblev1alpha1.Role),
+		resolved: make(map[string]hubblev1alpha1.Role),
+		visiting: make(map[string]bool),
+	}
+	for _, role := range roles {
+		f.declared[role.Name] = role
+	}
+
+	for _, role := range roles {
+		if _, err := f.flatten(role.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return f.resolved, nil
+}
+
+//Lists the effective roles of the roles in the instance that extend other roles, as they are shown in the status.
+func effectiveRoles(instance *hubblev1alpha1.HubbleRbac, flattened map[string]hubblev1alpha1.Role) []hubblev1alpha1.Role {
+	var result []hubblev1alpha1.Role
+	for _, role := range instance.Spec.Roles {
+		if len(role.Extends) == 0 {
+			continue
+		}
+		if effective, ok := flattened[role.Name]; ok {
+			result = append(result, effective)
+		}
+	}
+	return result
+}
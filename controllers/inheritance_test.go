@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Inheritance_RolesAreFlattened(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Databases = append(instance.Spec.Databases, hubblev1alpha1.Database{Name: "credit", Cluster: "hubble-credit", Database: "prod"})
+	instance.Spec.Roles = []hubblev1alpha1.Role{
+		{Name: "SeniorAnalyst", Extends: []string{"Analyst", "CreditAnalyst"}, DatawarehouseGrants: []string{"public_internal"}},
+		{Name: "Analyst", Databases: []string{"unstable"}, DatawarehouseGrants: []string{"public"}, DatalakeGrants: []string{"lw-go-events"}, Policies: []string{"access-to-secrets"}},
+		{Name: "CreditAnalyst", Extends: []string{"Analyst"}, Databases: []string{"credit"}, DatawarehouseGrants: []string{"public", "credit"}},
+	}
+	instance.Spec.Users[0].Roles = []string{"SeniorAnalyst"}
+
+	model, err := buildHubbleModel(instance, time.Now())
+	assert.NoError(err)
+
+	role := model.Users[0].AssignedTo[0]
+	assert.Equal("SeniorAnalyst", role.Name)
+	assert.Equal([]hubble.DataSet{"public_internal", "public", "credit"}, role.Acl, "grants are inherited once")
+	assert.Len(role.GrantedDatabases, 2)
+	assert.Len(role.GrantedGlueDatabases, 1)
+	assert.Len(role.Policies, 1)
+
+	flattened, err := flattenRoles(instance.Spec.Roles)
+	assert.NoError(err)
+	effective := effectiveRoles(instance, flattened)
+	assert.Len(effective, 2, "only roles that extend other roles are shown in the status")
+	assert.Equal([]string{"credit", "unstable"}, effective[1].Databases)
+}
+
+func Test_Inheritance_Cycles(t *testing.T) {
+
+	assert := assert.New(t)
+
+	_, err := flattenRoles([]hubblev1alpha1.Role{
+		{Name: "A", Extends: []string{"B"}},
+		{Name: "B", Extends: []string{"C"}},
+		{Name: "C", Extends: []string{"A"}},
+	})
+	assert.EqualError(err, "role A extends itself: A -> B -> C -> A")
+
+	_, err = flattenRoles([]hubblev1alpha1.Role{{Name: "A", Extends: []string{"A"}}})
+	assert.Error(err)
+
+	_, err = flattenRoles([]hubblev1alpha1.Role{{Name: "A", Extends: []string{"Missing"}}})
+	assert.EqualError(err, "no such role: Missing")
+
+	instance := validSpec()
+	instance.Spec.Roles[0].Extends = []string{"BiAnalyst"}
+	assert.NotEmpty(validateHubbleRbac(instance, nil), "cycles are rejected by the validation")
+
+	instance.Spec.Roles[0].Extends = []string{"Missing"}
+	errs := validateHubbleRbac(instance, nil)
+	assert.Len(errs, 1)
+	assert.Equal("spec.roles[0].extends[0]", errs[0].Field)
+}
+
+func Test_Inheritance_HighestPrivilegeIsKept(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles = []hubblev1alpha1.Role{
+		{Name: "Analyst", Databases: []string{"unstable"}, DatawarehouseGrants: []string{"public_bi", "credit"}, Privileges: map[string]hubblev1alpha1.PrivilegeLevel{"public_bi": "write", "credit": "owner"}},
+		{Name: "Engineer", Extends: []string{"Analyst"}, DatawarehouseGrants: []string{"public_bi", "credit"}, Privileges: map[string]hubblev1alpha1.PrivilegeLevel{"public_bi": "owner", "credit": "read"}},
+	}
+	instance.Spec.Users[0].Roles = []string{"Engineer"}
+
+	model, err := buildHubbleModel(instance, time.Now())
+	assert.NoError(err)
+
+	role := model.Users[0].AssignedTo[0]
+	assert.Equal(hubble.OwnerPrivilege, role.Privileges["public_bi"])
+	assert.Equal(hubble.OwnerPrivilege, role.Privileges["credit"], "an inherited privilege is not lowered")
+}
+
+func Test_Inheritance_TableGrantsAreMerged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	flattened, err := flattenRoles([]hubblev1alpha1.Role{
+		{Name: "Support", TableGrants: []hubblev1alpha1.TableGrant{{Schema: "pii", Table: "customers", Columns: []string{"id", "name"}}}},
+		{Name: "Fraud", Extends: []string{"Support"}, TableGrants: []hubblev1alpha1.TableGrant{
+			{Schema: "pii", Table: "customers", Columns: []string{"id", "ssn"}},
+			{Schema: "pii", Table: "transactions"},
+		}},
+		{Name: "Compliance", Extends: []string{"Fraud"}, TableGrants: []hubblev1alpha1.TableGrant{{Schema: "pii", Table: "customers"}}},
+	})
+	assert.NoError(err)
+
+	assert.Equal([]hubblev1alpha1.TableGrant{
+		{Schema: "pii", Table: "customers", Columns: []string{"id", "ssn", "name"}},
+		{Schema: "pii", Table: "transactions"},
+	}, flattened["Fraud"].TableGrants)
+	assert.Equal([]hubblev1alpha1.TableGrant{
+		{Schema: "pii", Table: "customers"},
+		{Schema: "pii", Table: "transactions"},
+	}, flattened["Compliance"].TableGrants, "granting the whole table includes all columns")
+}
+
+func Test_Inheritance_PolicyStatementsAreInherited(t *testing.T) {
+
+	assert := assert.New(t)
+
+	readReports := hubblev1alpha1.PolicyStatement{Effect: "Allow", Actions: []string{"s3:GetObject"}, Resources: []string{"arn:aws:s3:::lunar-reports/*"}}
+	runQueries := hubblev1alpha1.PolicyStatement{Effect: "Allow", Actions: []string{"athena:StartQueryExecution"}, Resources: []string{"*"}}
+
+	flattened, err := flattenRoles([]hubblev1alpha1.Role{
+		{Name: "Analyst", PolicyStatements: []hubblev1alpha1.PolicyStatement{readReports}},
+		{Name: "Engineer", Extends: []string{"Analyst"}, PolicyStatements: []hubblev1alpha1.PolicyStatement{runQueries, readReports}},
+	})
+	assert.NoError(err)
+
+	assert.Equal([]hubblev1alpha1.PolicyStatement{runQueries, readReports}, flattened["Engineer"].PolicyStatements, "statements are inherited once")
+}
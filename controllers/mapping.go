@@ -5,9 +5,13 @@ import (
 	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	"strings"
+	"time"
 )
 
-func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
+//Roles are flattened, so a role is granted everything of the roles it extends.
+//Members of a team are assigned the roles of the team, a role is only assigned once no matter how many times it is given to a user.
+//Role assignments are only included in the model if they are active at the given point in time.
+func buildHubbleModel(users *hubblev1alpha1.HubbleRbac, now time.Time) (hubble.Model, error) {
 
 	model := hubble.Model{}
 
@@ -29,19 +33,41 @@ func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
 		policyMap[policy.Name] = model.AddPolicyReference(policy.Arn)
 	}
 
-	for _, role := range users.Spec.Roles {
+	flattened, err := flattenRoles(users.Spec.Roles)
+	if err != nil {
+		return model, err
+	}
+
+	datalakeLocations := make(map[string]string)
+	for _, datalake := range users.Spec.Datalakes {
+		datalakeLocations[datalake.Name] = datalake.Location
+	}
+
+	for _, role := range flattened {
 		for _, name := range role.DatalakeGrants {
 			datalakeGrantsMap[name] = &hubble.GlueDatabase{
 				ShortName: strings.ReplaceAll(name, "-", ""),
 				Name:      name,
+				Location:  datalakeLocations[name],
 			}
 		}
 	}
 
-	for _, role := range users.Spec.Roles {
+	for _, declared := range users.Spec.Roles {
+		role := flattened[declared.Name]
+
 		var acl []hubble.DataSet
+		privileges := make(map[hubble.DataSet]hubble.Privilege)
 		for _, name := range role.DatawarehouseGrants {
 			acl = append(acl, hubble.DataSet(name))
+			if level, ok := role.Privileges[name]; ok {
+				privileges[hubble.DataSet(name)] = hubble.Privilege(level)
+			}
+		}
+
+		var tableGrants []hubble.TableGrant
+		for _, grant := range role.TableGrants {
+			tableGrants = append(tableGrants, hubble.TableGrant{DataSet: hubble.DataSet(grant.Schema), Table: grant.Table, Columns: grant.Columns})
 		}
 
 		var datalakeGrants []*hubble.GlueDatabase
@@ -77,19 +103,43 @@ func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
 			policies = append(policies, policy)
 		}
 
+		var policyStatements []hubble.PolicyStatement
+		for _, statement := range role.PolicyStatements {
+			policyStatements = append(policyStatements, hubble.PolicyStatement{Sid: statement.Sid, Effect: statement.Effect, Actions: statement.Actions, Resources: statement.Resources})
+		}
+
+		var sessionDuration time.Duration
+		if role.SessionDuration != nil {
+			sessionDuration = role.SessionDuration.Duration
+		}
+
 		r := &hubble.Role{
 			Name:                 role.Name,
 			GrantedDatabases:     databases,
 			GrantedDevDatabases:  devDatabases,
 			GrantedGlueDatabases: datalakeGrants,
 			Acl:                  acl,
+			Privileges:           privileges,
+			TableGrants:          tableGrants,
+			SessionDuration:      sessionDuration,
 			Policies:             policies,
+			PolicyStatements:     policyStatements,
 		}
 
 		model.Roles = append(model.Roles, r)
 		roleMap[role.Name] = r
 	}
 
+	teamsByMember := make(map[string][]hubblev1alpha1.Team)
+	for _, team := range users.Spec.Teams {
+		for _, member := range team.Members {
+			if findUser(users, member) == nil {
+				return model, fmt.Errorf("no such user: %s in team %s", member, team.Name)
+			}
+			teamsByMember[member] = append(teamsByMember[member], team)
+		}
+	}
+
 	for _, user := range users.Spec.Users {
 		a := model.AddUser(user.Name, user.Email)
 
@@ -100,7 +150,90 @@ func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
 			}
 			a.Assign(role)
 		}
+
+		for _, assignment := range user.RoleAssignments {
+			role, ok := roleMap[assignment.Role]
+			if !ok {
+				return model, fmt.Errorf("no such role: %s", assignment.Role)
+			}
+			if isActive(assignment, now) && !isAssigned(a, role) {
+				a.Assign(role)
+			}
+		}
+
+		for _, team := range teamsByMember[user.Name] {
+			for _, r := range team.Roles {
+				role, ok := roleMap[r]
+				if !ok {
+					return model, fmt.Errorf("no such role: %s in team %s", r, team.Name)
+				}
+				if !isAssigned(a, role) {
+					a.Assign(role)
+				}
+			}
+		}
 	}
 
 	return model, nil
 }
+
+func isAssigned(user *hubble.User, role *hubble.Role) bool {
+	for _, r := range user.AssignedTo {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+//Adds what is needed to revoke the access granted by a deleted HubbleRbac to the model of the remaining HubbleRbacs.
+//Its users are kept without any roles unless they are declared elsewhere, so their google SAML assignments are cleared,
+//and its databases are kept so the clusters they reside on are still reconciled. Everything else is removed by the appliers.
+//References are not resolved, so this never fails even if the spec is invalid.
+func revokeAccess(model *hubble.Model, instance *hubblev1alpha1.HubbleRbac) {
+
+	for _, database := range instance.Spec.Databases {
+		if !containsDatabase(model, database.Cluster, database.Database) {
+			model.AddDatabase(database.Cluster, database.Database)
+		}
+	}
+
+	for _, database := range instance.Spec.DevDatabases {
+		if !containsDevDatabase(model, database.Cluster) {
+			model.AddDevDatabase(database.Cluster)
+		}
+	}
+
+	for _, user := range instance.Spec.Users {
+		if !containsUser(model, user.Email) {
+			model.AddUser(user.Name, user.Email)
+		}
+	}
+}
+
+func containsDatabase(model *hubble.Model, clusterIdentifier string, name string) bool {
+	for _, database := range model.Databases {
+		if database.ClusterIdentifier == clusterIdentifier && database.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDevDatabase(model *hubble.Model, clusterIdentifier string) bool {
+	for _, database := range model.DevDatabases {
+		if database.ClusterIdentifier == clusterIdentifier {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUser(model *hubble.Model, email string) bool {
+	for _, user := range model.Users {
+		if user.Email == email {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+func qualifiedName(instance *hubblev1alpha1.HubbleRbac) string {
+	return fmt.Sprintf("%s/%s", instance.Namespace, instance.Name)
+}
+
+//Keeps track of which HubbleRbac first declared a definition, so conflicting definitions can be reported with both owners.
+type declarations struct {
+	kind        string
+	definitions map[string]interface{}
+	owners      map[string]string
+	errs        []error
+}
+
+func newDeclarations(kind string) *declarations {
+	return &declarations{kind: kind, definitions: make(map[string]interface{}), owners: make(map[string]string)}
+}
+
+//Returns true if the definition has not been declared before and should be added to the merged spec.
+//Identical definitions in several HubbleRbacs are allowed and are only added once.
+func (d *declarations) declare(name string, definition interface{}, owner string) bool {
+	existing, ok := d.definitions[name]
+	if !ok {
+		d.definitions[name] = definition
+		d.owners[name] = owner
+		return true
+	}
+	if !reflect.DeepEqual(existing, definition) {
+		d.errs = append(d.errs, fmt.Errorf("%s %s is declared differently in %s and %s", d.kind, name, d.owners[name], owner))
+	}
+	return false
+}
+
+// mergeHubbleRbacs combines the specs of all the given HubbleRbacs into a single spec that describes the complete desired state.
+// Roles may reference databases and policies declared in another HubbleRbac.
+// It fails if two HubbleRbacs declare the same user, role, database, policy, team or datalake differently.
+func mergeHubbleRbacs(instances []*hubblev1alpha1.HubbleRbac) (*hubblev1alpha1.HubbleRbac, error) {
+
+	sorted := make([]*hubblev1alpha1.HubbleRbac, len(instances))
+	copy(sorted, instances)
+	sort.Slice(sorted, func(i, j int) bool {
+		return qualifiedName(sorted[i]) < qualifiedName(sorted[j])
+	})
+
+	merged := &hubblev1alpha1.HubbleRbac{}
+
+	users := newDeclarations("user")
+	emails := newDeclarations("email")
+	roles := newDeclarations("role")
+	policies := newDeclarations("policy")
+	databases := newDeclarations("database")
+	devDatabases := newDeclarations("developer database")
+	teams := newDeclarations("team")
+	datalakes := newDeclarations("datalake")
+
+	for _, instance := range sorted {
+		owner := qualifiedName(instance)
+
+		for _, user := range instance.Spec.Users {
+			if users.declare(user.Name, user, owner) {
+				merged.Spec.Users = append(merged.Spec.Users, user)
+			}
+			emails.declare(user.Email, user.Name, owner)
+		}
+		for _, role := range instance.Spec.Roles {
+			if roles.declare(role.Name, role, owner) {
+				merged.Spec.Roles = append(merged.Spec.Roles, role)
+			}
+		}
+		for _, policy := range instance.Spec.Policies {
+			if policies.declare(policy.Name, policy, owner) {
+				merged.Spec.Policies = append(merged.Spec.Policies, policy)
+			}
+		}
+		for _, database := range instance.Spec.Databases {
+			if databases.declare(database.Name, database, owner) {
+				merged.Spec.Databases = append(merged.Spec.Databases, database)
+			}
+		}
+		for _, database := range instance.Spec.DevDatabases {
+			if devDatabases.declare(database.Name, database, owner) {
+				merged.Spec.DevDatabases = append(merged.Spec.DevDatabases, database)
+			}
+		}
+		for _, team := range instance.Spec.Teams {
+			if teams.declare(team.Name, team, owner) {
+				merged.Spec.Teams = append(merged.Spec.Teams, team)
+			}
+		}
+		for _, datalake := range instance.Spec.Datalakes {
+			if datalakes.declare(datalake.Name, datalake, owner) {
+				merged.Spec.Datalakes = append(merged.Spec.Datalakes, datalake)
+			}
+		}
+	}
+
+	var errs []error
+	for _, d := range []*declarations{users, emails, roles, policies, databases, devDatabases, teams, datalakes} {
+		errs = append(errs, d.errs...)
+	}
+
+	return merged, utilerrors.NewAggregate(errs)
+}
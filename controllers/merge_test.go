@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func hubbleRbac(name string, spec hubblev1alpha1.HubbleRbacSpec) *hubblev1alpha1.HubbleRbac {
+	return &hubblev1alpha1.HubbleRbac{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "datascience", Name: name},
+		Spec:       spec,
+	}
+}
+
+func Test_Merge_CombinesSpecs(t *testing.T) {
+
+	assert := assert.New(t)
+
+	database := hubblev1alpha1.Database{Name: "unstable", Cluster: "hubble-unstable", Database: "prod"}
+
+	platform := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{
+		Databases: []hubblev1alpha1.Database{database},
+		Roles:     []hubblev1alpha1.Role{{Name: "BiAnalyst", Databases: []string{"unstable"}}},
+		Users:     []hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app", Roles: []string{"BiAnalyst"}}},
+	})
+
+	credit := hubbleRbac("credit", hubblev1alpha1.HubbleRbacSpec{
+		Databases: []hubblev1alpha1.Database{database},
+		Roles:     []hubblev1alpha1.Role{{Name: "CreditAnalyst", Databases: []string{"unstable"}}},
+		Users:     []hubblev1alpha1.User{{Name: "nra", Email: "nra@lunar.app", Roles: []string{"CreditAnalyst", "BiAnalyst"}}},
+	})
+
+	merged, err := mergeHubbleRbacs([]*hubblev1alpha1.HubbleRbac{platform, credit})
+	assert.NoError(err)
+
+	assert.Len(merged.Spec.Databases, 1, "identical databases are only declared once")
+	assert.Len(merged.Spec.Roles, 2)
+	assert.Len(merged.Spec.Users, 2)
+
+	model, err := buildHubbleModel(merged, time.Now())
+	assert.NoError(err, "roles declared in one HubbleRbac can be assigned in another")
+	assert.Len(model.Roles, 2)
+	assert.Len(model.Users, 2)
+}
+
+func Test_Merge_DetectsConflicts(t *testing.T) {
+
+	assert := assert.New(t)
+
+	first := hubbleRbac("first", hubblev1alpha1.HubbleRbacSpec{
+		Roles: []hubblev1alpha1.Role{{Name: "BiAnalyst", DatawarehouseGrants: []string{"bi"}}},
+		Users: []hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app"}},
+	})
+
+	second := hubbleRbac("second", hubblev1alpha1.HubbleRbacSpec{
+		Roles: []hubblev1alpha1.Role{{Name: "BiAnalyst", DatawarehouseGrants: []string{"core"}}},
+		Users: []hubblev1alpha1.User{{Name: "jwr2", Email: "jwr@lunar.app"}},
+	})
+
+	_, err := mergeHubbleRbacs([]*hubblev1alpha1.HubbleRbac{second, first})
+
+	assert.Error(err)
+	assert.Contains(err.Error(), "role BiAnalyst is declared differently in datascience/first and datascience/second")
+	assert.Contains(err.Error(), "email jwr@lunar.app is declared differently in datascience/first and datascience/second")
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+
+	"github.com/go-logr/logr"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//The number of changes listed for each system, so a large plan doesn't exceed the size limit of the status.
+const maxPlanItems = 50
+
+func firstPlanItems(items []string) []string {
+	if len(items) > maxPlanItems {
+		return items[:maxPlanItems]
+	}
+	return items
+}
+
+func plannedRedshiftTasks(plan *service.Plan) []hubblev1alpha1.PlannedRedshiftTask {
+	var result []hubblev1alpha1.PlannedRedshiftTask
+	for _, task := range plan.Redshift {
+		if len(result) == maxPlanItems {
+			break
+		}
+		result = append(result, hubblev1alpha1.PlannedRedshiftTask{
+			Type:       task.Type.String(),
+			Identifier: task.Identifier,
+			Cluster:    task.ClusterIdentifier,
+			Database:   task.DatabaseName,
+		})
+	}
+	return result
+}
+
+func plannedIamChanges(plan *service.Plan) []hubblev1alpha1.PlannedIamChange {
+	var result []hubblev1alpha1.PlannedIamChange
+	for _, change := range plan.Iam {
+		if len(result) == maxPlanItems {
+			break
+		}
+		result = append(result, hubblev1alpha1.PlannedIamChange{
+			Type: change.Type.ToString(),
+			Name: change.Name,
+			Role: change.Role,
+			Diff: change.Diff,
+		})
+	}
+	return result
+}
+
+func plannedGoogleChanges(plan *service.Plan) []hubblev1alpha1.PlannedGoogleChange {
+	var result []hubblev1alpha1.PlannedGoogleChange
+	for _, change := range plan.Google {
+		if len(result) == maxPlanItems {
+			break
+		}
+		planned := hubblev1alpha1.PlannedGoogleChange{
+			Email:        change.Email,
+			AddedRoles:   change.Added,
+			RemovedRoles: change.Removed,
+		}
+		if change.CurrentSessionDuration != change.DesiredSessionDuration {
+			planned.SessionDuration = change.DesiredSessionDuration
+		}
+		result = append(result, planned)
+	}
+	return result
+}
+
+func planStatus(plan *service.Plan, now time.Time) *hubblev1alpha1.PlanStatus {
+	return &hubblev1alpha1.PlanStatus{
+		PlannedAt:   metav1.NewTime(now),
+		Count:       plan.Count(),
+		Hash:        plan.Hash(),
+		Destructive: firstPlanItems(plan.Destructive()),
+		Redshift:    plannedRedshiftTasks(plan),
+		Iam:         plannedIamChanges(plan),
+		Google:      plannedGoogleChanges(plan),
+		BlastRadius: hubblev1alpha1.BlastRadius{
+			DroppedUsers:   plan.BlastRadius.DroppedUsers,
+			DeletedRoles:   plan.BlastRadius.DeletedRoles,
+			RevokedGrants:  plan.BlastRadius.RevokedGrants,
+			RemovedPercent: plan.BlastRadius.RemovedPercent,
+		},
+	}
+}
+
+func (r *HubbleRbacReconciler) setStatusPlanFailed(instance *hubblev1alpha1.HubbleRbac, err error, logger logr.Logger) {
+	instance.Status.Error = err.Error()
+	instance.Status.SetCondition(hubblev1alpha1.Condition{Type: hubblev1alpha1.ConditionReady, Status: hubblev1alpha1.ConditionFalse, Reason: "PlanFailed", Message: err.Error()})
+
+	r.updateStatus(instance, logger)
+}
+
+//Returns true if the changes have to be planned before they are applied.
+func (r *HubbleRbacReconciler) planBeforeApply() bool {
+	return !r.DryRun && (r.RequireApproval || r.Limits.Enabled())
+}
+
+//Plans the changes of the model and holds them back if they remove more than the limits allow, or if they are destructive and haven't been approved on one of the given HubbleRbacs.
+//Returns the plan that may be applied.
+func (r *HubbleRbacReconciler) checkPlan(model hubble.Model, target *hubblev1alpha1.HubbleRbac, instances []*hubblev1alpha1.HubbleRbac, now time.Time) (*service.Plan, error) {
+	plan, err := r.Applier.Plan(model)
+	if err != nil {
+		r.Log.Error(err, "unable to plan changes")
+		for _, instance := range instances {
+			r.setStatusPlanFailed(instance, err, r.Log)
+		}
+		return nil, err
+	}
+
+	err = r.checkBlastRadius(plan, target, instances, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.RequireApproval {
+		err = r.checkApproval(plan, target, instances, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+//Applies the model, only making the changes of the given plan if it has been checked.
+//Nothing is applied while records of an earlier apply are waiting to be written to the audit log, and the apply fails if its own records can't be written.
+func (r *HubbleRbacReconciler) apply(model hubble.Model, plan *service.Plan) (*service.ApplyResult, error) {
+	err := r.Audit.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	var result *service.ApplyResult
+	if plan == nil {
+		result, err = r.Applier.Apply(model, r.DryRun)
+	} else {
+		result, err = r.Applier.ApplyPlan(model, plan.Hash())
+	}
+
+	auditErr := r.Audit.Flush()
+	if err == nil {
+		err = auditErr
+	}
+	return result, err
+}
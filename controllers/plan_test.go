@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PlanStatus(t *testing.T) {
+
+	assert := assert.New(t)
+
+	plan := &service.Plan{
+		Iam: []iam.Change{
+			{Type: iam.RoleCreated, Name: "BiAnalyst"},
+			{Type: iam.PolicyCreated, Name: "jwr_bianalyst", Role: "BiAnalyst", Diff: "+{}"},
+		},
+		Google: []google.RoleChange{
+			{Email: "jwr@lunar.app", Added: []string{"BiAnalyst"}, CurrentSessionDuration: 3600, DesiredSessionDuration: 3600},
+			{Email: "nra@lunar.app", CurrentSessionDuration: 3600, DesiredSessionDuration: 7200},
+		},
+	}
+	for i := 0; i < 60; i++ {
+		plan.Redshift = append(plan.Redshift, redshiftCore.PlannedTask{Type: redshiftCore.CreateUser, Identifier: fmt.Sprintf("user%d", i), ClusterIdentifier: "dev"})
+	}
+
+	status := planStatus(plan, time.Now())
+
+	assert.Equal(64, status.Count)
+	assert.Len(status.Redshift, maxPlanItems, "only the first changes are listed")
+	assert.Equal(hubblev1alpha1.PlannedRedshiftTask{Type: "CreateUser", Identifier: "user0", Cluster: "dev"}, status.Redshift[0])
+	assert.Equal([]hubblev1alpha1.PlannedIamChange{
+		{Type: "RoleCreated", Name: "BiAnalyst"},
+		{Type: "PolicyCreated", Name: "jwr_bianalyst", Role: "BiAnalyst", Diff: "+{}"},
+	}, status.Iam)
+	assert.Equal([]hubblev1alpha1.PlannedGoogleChange{
+		{Email: "jwr@lunar.app", AddedRoles: []string{"BiAnalyst"}},
+		{Email: "nra@lunar.app", SessionDuration: 7200},
+	}, status.Google, "the session duration is only listed if it changes")
+}
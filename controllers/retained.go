@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+//The spec of a HubbleRbac deleted with the Retain policy is kept in a ConfigMap in its namespace, so the resources it declared stay part of the desired state.
+const retainedLabel = "hubble.lunar.tech/retained"
+const retainedFromAnnotation = "hubble.lunar.tech/retained-from"
+const retainedSpecKey = "spec"
+
+func retainedConfigMapName(instance *hubblev1alpha1.HubbleRbac) string {
+	return "hubble-rbac-retained-" + instance.Name
+}
+
+//Stores the spec of a HubbleRbac that is being deleted with the Retain policy.
+func (r *HubbleRbacReconciler) retain(instance *hubblev1alpha1.HubbleRbac) error {
+	spec, err := json.Marshal(instance.Spec)
+	if err != nil {
+		return err
+	}
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: instance.Namespace, Name: retainedConfigMapName(instance)}}
+	_, err = controllerutil.CreateOrUpdate(context.TODO(), r.Client, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = make(map[string]string)
+		}
+		if configMap.Annotations == nil {
+			configMap.Annotations = make(map[string]string)
+		}
+		configMap.Labels[retainedLabel] = "true"
+		configMap.Annotations[retainedFromAnnotation] = instance.Name
+		configMap.Data = map[string]string{retainedSpecKey: string(spec)}
+		return nil
+	})
+	return err
+}
+
+//Removes the spec retained from an earlier HubbleRbac with the same name, as it is replaced by the HubbleRbac once it exists again or revokes its access.
+func (r *HubbleRbacReconciler) forgetRetained(instance *hubblev1alpha1.HubbleRbac) error {
+	configMap := &corev1.ConfigMap{}
+
+	err := r.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: retainedConfigMapName(instance)}, configMap)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.Log.Info("removing the retained spec of an earlier HubbleRbac with the same name", "namespace", instance.Namespace, "name", instance.Name)
+	err = r.Delete(context.TODO(), configMap)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+//Lists the retained HubbleRbacs, leaving out the ones that have been created again since, as they are part of the active HubbleRbacs.
+func (r *HubbleRbacReconciler) listRetained(active []*hubblev1alpha1.HubbleRbac) ([]*hubblev1alpha1.HubbleRbac, error) {
+	list := &corev1.ConfigMapList{}
+
+	err := r.List(context.TODO(), list, client.MatchingLabels{retainedLabel: "true"})
+	if err != nil {
+		return nil, err
+	}
+
+	activeNames := make(map[string]bool)
+	for _, instance := range active {
+		activeNames[qualifiedName(instance)] = true
+	}
+
+	var result []*hubblev1alpha1.HubbleRbac
+	for _, configMap := range list.Items {
+		instance := &hubblev1alpha1.HubbleRbac{ObjectMeta: metav1.ObjectMeta{Namespace: configMap.Namespace, Name: configMap.Annotations[retainedFromAnnotation]}}
+		if activeNames[qualifiedName(instance)] {
+			continue
+		}
+		err = json.Unmarshal([]byte(configMap.Data[retainedSpecKey]), &instance.Spec)
+		if err != nil {
+			r.Log.Error(err, "ignoring malformed retained HubbleRbac", "namespace", configMap.Namespace, "name", configMap.Name)
+			continue
+		}
+		result = append(result, instance)
+	}
+	return result, nil
+}
+
+// mergeRetained adds the declarations of retained HubbleRbacs to the merged spec of the active ones.
+// The active HubbleRbacs take precedence: a retained user, role, database, policy, team or datalake is only added if no active HubbleRbac declares it.
+func mergeRetained(merged *hubblev1alpha1.HubbleRbac, retained []*hubblev1alpha1.HubbleRbac) {
+
+	sorted := make([]*hubblev1alpha1.HubbleRbac, len(retained))
+	copy(sorted, retained)
+	sort.Slice(sorted, func(i, j int) bool {
+		return qualifiedName(sorted[i]) < qualifiedName(sorted[j])
+	})
+
+	declared := make(map[string]bool)
+	isNew := func(kind string, name string) bool {
+		key := kind + "/" + name
+		if declared[key] {
+			return false
+		}
+		declared[key] = true
+		return true
+	}
+
+	for _, user := range merged.Spec.Users {
+		isNew("user", user.Name)
+		isNew("email", user.Email)
+	}
+	for _, role := range merged.Spec.Roles {
+		isNew("role", role.Name)
+	}
+	for _, policy := range merged.Spec.Policies {
+		isNew("policy", policy.Name)
+	}
+	for _, database := range merged.Spec.Databases {
+		isNew("database", database.Name)
+	}
+	for _, database := range merged.Spec.DevDatabases {
+		isNew("devDatabase", database.Name)
+	}
+	for _, team := range merged.Spec.Teams {
+		isNew("team", team.Name)
+	}
+	for _, datalake := range merged.Spec.Datalakes {
+		isNew("datalake", datalake.Name)
+	}
+
+	for _, instance := range sorted {
+		for _, user := range instance.Spec.Users {
+			if !declared["user/"+user.Name] && !declared["email/"+user.Email] {
+				isNew("user", user.Name)
+				isNew("email", user.Email)
+				merged.Spec.Users = append(merged.Spec.Users, user)
+			}
+		}
+		for _, role := range instance.Spec.Roles {
+			if isNew("role", role.Name) {
+				merged.Spec.Roles = append(merged.Spec.Roles, role)
+			}
+		}
+		for _, policy := range instance.Spec.Policies {
+			if isNew("policy", policy.Name) {
+				merged.Spec.Policies = append(merged.Spec.Policies, policy)
+			}
+		}
+		for _, database := range instance.Spec.Databases {
+			if isNew("database", database.Name) {
+				merged.Spec.Databases = append(merged.Spec.Databases, database)
+			}
+		}
+		for _, database := range instance.Spec.DevDatabases {
+			if isNew("devDatabase", database.Name) {
+				merged.Spec.DevDatabases = append(merged.Spec.DevDatabases, database)
+			}
+		}
+		for _, team := range instance.Spec.Teams {
+			if isNew("team", team.Name) {
+				merged.Spec.Teams = append(merged.Spec.Teams, team)
+			}
+		}
+		for _, datalake := range instance.Spec.Datalakes {
+			if isNew("datalake", datalake.Name) {
+				merged.Spec.Datalakes = append(merged.Spec.Datalakes, datalake)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_Retain_KeepsDeclarationsOfDeletedHubbleRbac(t *testing.T) {
+
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(clientgoscheme.AddToScheme(scheme))
+	assert.NoError(hubblev1alpha1.AddToScheme(scheme))
+
+	database := hubblev1alpha1.Database{Name: "unstable", Cluster: "hubble-unstable", Database: "prod"}
+
+	platform := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{
+		Databases:      []hubblev1alpha1.Database{database},
+		Roles:          []hubblev1alpha1.Role{{Name: "BiAnalyst", Databases: []string{"unstable"}}},
+		Users:          []hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app", Roles: []string{"BiAnalyst"}}},
+		DeletionPolicy: hubblev1alpha1.DeletionPolicyRetain,
+	})
+	platform.Finalizers = []string{finalizerName}
+
+	credit := hubbleRbac("credit", hubblev1alpha1.HubbleRbacSpec{
+		Databases: []hubblev1alpha1.Database{database},
+		Roles:     []hubblev1alpha1.Role{{Name: "CreditAnalyst", Databases: []string{"unstable"}}},
+		Users:     []hubblev1alpha1.User{{Name: "nra", Email: "nra@lunar.app", Roles: []string{"CreditAnalyst"}}},
+	})
+	credit.Finalizers = []string{finalizerName}
+
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme, platform, credit), Log: logf.Log, Scheme: scheme}
+
+	//the finalizer of the deleted HubbleRbac is removed, after which it is gone
+	now := metav1.Now()
+	platform.DeletionTimestamp = &now
+	_, err := r.reconcileDelete(platform)
+	assert.NoError(err)
+	assert.NoError(r.Delete(context.TODO(), platform))
+
+	//the other HubbleRbac is then reconciled
+	instances, err := r.listActive(credit)
+	assert.NoError(err)
+	assert.Len(instances, 1)
+
+	merged, err := mergeHubbleRbacs(instances)
+	assert.NoError(err)
+	retained, err := r.listRetained(instances)
+	assert.NoError(err)
+	mergeRetained(merged, retained)
+
+	model, err := buildHubbleModel(merged, time.Now())
+	assert.NoError(err)
+	assert.Len(model.Users, 2, "the users of the retained HubbleRbac are still desired")
+	assert.Len(model.Roles, 2, "the roles of the retained HubbleRbac are still desired")
+
+	//creating the HubbleRbac again takes over its retained declarations
+	recreated := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	retained, err = r.listRetained(append(instances, recreated))
+	assert.NoError(err)
+	assert.Empty(retained)
+}
+
+func Test_Retain_RecreatedHubbleRbacRevokesRetainedDeclarations(t *testing.T) {
+
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(clientgoscheme.AddToScheme(scheme))
+	assert.NoError(hubblev1alpha1.AddToScheme(scheme))
+
+	spec := hubblev1alpha1.HubbleRbacSpec{
+		Roles:          []hubblev1alpha1.Role{{Name: "BiAnalyst"}},
+		Users:          []hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app", Roles: []string{"BiAnalyst"}}},
+		DeletionPolicy: hubblev1alpha1.DeletionPolicyRetain,
+	}
+	platform := hubbleRbac("platform", spec)
+	platform.Finalizers = []string{finalizerName}
+
+	//the controller is paused, so the revocation is held back after the retained spec has been removed
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme, platform), Log: logf.Log, Scheme: scheme, Paused: true}
+
+	now := metav1.Now()
+	platform.DeletionTimestamp = &now
+	_, err := r.reconcileDelete(platform)
+	assert.NoError(err)
+
+	retained, err := r.listRetained(nil)
+	assert.NoError(err)
+	assert.Len(retained, 1)
+
+	//the HubbleRbac is created again and deleted with the Revoke policy
+	recreated := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{DeletionPolicy: hubblev1alpha1.DeletionPolicyRevoke})
+	recreated.Finalizers = []string{finalizerName}
+	recreated.DeletionTimestamp = &now
+	_, err = r.reconcileDelete(recreated)
+	assert.NoError(err)
+
+	retained, err = r.listRetained(nil)
+	assert.NoError(err)
+	assert.Empty(retained, "the retained declarations are revoked along with the HubbleRbac")
+
+	//a HubbleRbac that exists again replaces its retained spec
+	assert.NoError(r.retain(platform))
+	assert.NoError(r.forgetRetained(recreated))
+	retained, err = r.listRetained(nil)
+	assert.NoError(err)
+	assert.Empty(retained)
+	assert.NoError(r.forgetRetained(recreated), "there is nothing to remove")
+}
+
+func Test_MergeRetained_ActiveDeclarationsTakePrecedence(t *testing.T) {
+
+	assert := assert.New(t)
+
+	merged := hubbleRbac("merged", hubblev1alpha1.HubbleRbacSpec{
+		Roles: []hubblev1alpha1.Role{{Name: "BiAnalyst", DatawarehouseGrants: []string{"bi"}}},
+		Users: []hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app"}},
+	})
+
+	retained := hubbleRbac("retired", hubblev1alpha1.HubbleRbacSpec{
+		Roles: []hubblev1alpha1.Role{{Name: "BiAnalyst", DatawarehouseGrants: []string{"core"}}, {Name: "CreditAnalyst"}},
+		Users: []hubblev1alpha1.User{{Name: "jwr2", Email: "jwr@lunar.app"}, {Name: "nra", Email: "nra@lunar.app"}},
+	})
+
+	mergeRetained(merged, []*hubblev1alpha1.HubbleRbac{retained})
+
+	assert.Equal([]hubblev1alpha1.Role{{Name: "BiAnalyst", DatawarehouseGrants: []string{"bi"}}, {Name: "CreditAnalyst"}}, merged.Spec.Roles)
+	assert.Equal([]hubblev1alpha1.User{{Name: "jwr", Email: "jwr@lunar.app"}, {Name: "nra", Email: "nra@lunar.app"}}, merged.Spec.Users, "a retained user with the email of an active user is left out")
+}
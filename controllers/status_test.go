@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_UpdateStatus_OnlyTimestampsChanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(clientgoscheme.AddToScheme(scheme))
+	assert.NoError(hubblev1alpha1.AddToScheme(scheme))
+
+	instance := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	r := &HubbleRbacReconciler{Client: fake.NewFakeClientWithScheme(scheme, instance), Log: logf.Log, Scheme: scheme}
+
+	applied := metav1.NewTime(time.Now().Add(-time.Hour))
+	instance.Status.LastAppliedTime = &applied
+	instance.Status.ManagedUsers = 2
+	r.updateStatus(instance, r.Log)
+
+	stored := &hubblev1alpha1.HubbleRbac{}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	assert.NoError(r.Get(context.TODO(), key, stored))
+	version := stored.ResourceVersion
+
+	now := metav1.Now()
+	stored.Status.LastAppliedTime = &now
+	r.updateStatus(stored, r.Log)
+
+	assert.NoError(r.Get(context.TODO(), key, stored))
+	assert.Equal(version, stored.ResourceVersion, "a status that only has new timestamps is not stored")
+
+	stored.Status.ManagedUsers = 3
+	r.updateStatus(stored, r.Log)
+
+	assert.NoError(r.Get(context.TODO(), key, stored))
+	assert.NotEqual(version, stored.ResourceVersion)
+	assert.Equal(3, stored.Status.ManagedUsers)
+}
+
+func Test_AnnotationsChanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	old := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	approved := old.DeepCopy()
+	approved.Annotations = map[string]string{hubblev1alpha1.ApprovedPlanAnnotation: "9b1f2c3d4e5f6a7b"}
+	statusOnly := old.DeepCopy()
+	statusOnly.Status.ManagedUsers = 2
+
+	assert.True(annotationsChanged.Update(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: approved, ObjectNew: approved}))
+	assert.False(annotationsChanged.Update(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: statusOnly, ObjectNew: statusOnly}))
+}
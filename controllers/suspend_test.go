@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"testing"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Suspend(t *testing.T) {
+
+	assert := assert.New(t)
+
+	platform := hubbleRbac("platform", hubblev1alpha1.HubbleRbacSpec{})
+	credit := hubbleRbac("credit", hubblev1alpha1.HubbleRbacSpec{Suspend: true})
+
+	r := &HubbleRbacReconciler{}
+	assert.Empty(r.suspendedBy([]*hubblev1alpha1.HubbleRbac{platform}))
+	assert.Equal("suspended by datascience/credit", r.suspendedBy([]*hubblev1alpha1.HubbleRbac{platform, credit}), "suspending one HubbleRbac suspends all of them")
+
+	r.Paused = true
+	assert.Equal("the controller is paused", r.suspendedBy([]*hubblev1alpha1.HubbleRbac{platform}))
+}
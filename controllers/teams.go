@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"sort"
+
+	"github.com/go-logr/logr"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+)
+
+// teamAssignments lists the roles that the teams of the instance assign to their members, along with the teams each one comes from.
+// The list is sorted by user and role.
+func teamAssignments(instance *hubblev1alpha1.HubbleRbac) []hubblev1alpha1.TeamAssignment {
+	type key struct{ user, role string }
+
+	teams := make(map[key][]string)
+	var keys []key
+
+	for _, team := range instance.Spec.Teams {
+		for _, member := range team.Members {
+			for _, role := range team.Roles {
+				k := key{user: member, role: role}
+				if _, ok := teams[k]; !ok {
+					keys = append(keys, k)
+				}
+				teams[k] = appendMissing(teams[k], team.Name)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].user != keys[j].user {
+			return keys[i].user < keys[j].user
+		}
+		return keys[i].role < keys[j].role
+	})
+
+	var result []hubblev1alpha1.TeamAssignment
+	for _, k := range keys {
+		result = append(result, hubblev1alpha1.TeamAssignment{User: k.user, Role: k.role, Teams: teams[k]})
+	}
+	return result
+}
+
+//Logs the roles that are assigned or no longer assigned through a team compared to the previous status, as an audit trail of team membership.
+func logTeamAssignmentChanges(logger logr.Logger, instance *hubblev1alpha1.HubbleRbac, previous []hubblev1alpha1.TeamAssignment, current []hubblev1alpha1.TeamAssignment) {
+	index := func(assignments []hubblev1alpha1.TeamAssignment) map[string]hubblev1alpha1.TeamAssignment {
+		result := make(map[string]hubblev1alpha1.TeamAssignment)
+		for _, assignment := range assignments {
+			result[assignment.User+"/"+assignment.Role] = assignment
+		}
+		return result
+	}
+	before := index(previous)
+	after := index(current)
+
+	for _, assignment := range current {
+		if _, ok := before[assignment.User+"/"+assignment.Role]; !ok {
+			logger.Info("role assigned through team", "hubbleRbac", qualifiedName(instance), "user", assignment.User, "role", assignment.Role, "teams", assignment.Teams)
+		}
+	}
+	for _, assignment := range previous {
+		if _, ok := after[assignment.User+"/"+assignment.Role]; !ok {
+			logger.Info("role no longer assigned through team", "hubbleRbac", qualifiedName(instance), "user", assignment.User, "role", assignment.Role, "teams", assignment.Teams)
+		}
+	}
+}
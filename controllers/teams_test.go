@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Teams_RolesAreAssignedToMembers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles = append(instance.Spec.Roles, hubblev1alpha1.Role{Name: "CreditAnalyst", Databases: []string{"unstable"}})
+	instance.Spec.Users = append(instance.Spec.Users, hubblev1alpha1.User{Name: "nra", Email: "nra@lunar.app"})
+	instance.Spec.Teams = []hubblev1alpha1.Team{
+		{Name: "bi", Members: []string{"jwr", "nra"}, Roles: []string{"BiAnalyst"}},
+		{Name: "credit", Members: []string{"nra"}, Roles: []string{"CreditAnalyst", "BiAnalyst"}},
+	}
+
+	model, err := buildHubbleModel(instance, time.Now())
+	assert.NoError(err)
+
+	assert.Len(model.Users[0].AssignedTo, 1, "a role assigned directly and through a team is only assigned once")
+	assert.Len(model.Users[1].AssignedTo, 2, "a role assigned through several teams is only assigned once")
+
+	assert.Equal([]hubblev1alpha1.TeamAssignment{
+		{User: "jwr", Role: "BiAnalyst", Teams: []string{"bi"}},
+		{User: "nra", Role: "BiAnalyst", Teams: []string{"bi", "credit"}},
+		{User: "nra", Role: "CreditAnalyst", Teams: []string{"credit"}},
+	}, teamAssignments(instance))
+}
+
+func Test_Teams_Validation(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Teams = []hubblev1alpha1.Team{
+		{Name: "bi", Members: []string{"jwr", "nra"}, Roles: []string{"BiAnalyst", "NoSuchRole"}},
+		{Name: "BI"},
+	}
+
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+
+	assert.Contains(fields, "spec.teams[0].members[1]", "missing member is reported")
+	assert.Contains(fields, "spec.teams[0].roles[1]", "missing role is reported")
+	assert.Contains(fields, "spec.teams[1].name", "duplicate team is reported")
+
+	other := hubbleRbac("credit", hubblev1alpha1.HubbleRbacSpec{
+		Users: []hubblev1alpha1.User{{Name: "nra", Email: "nra@lunar.app"}},
+	})
+	instance.Spec.Teams = instance.Spec.Teams[:1]
+	instance.Spec.Teams[0].Roles = []string{"BiAnalyst"}
+	assert.Empty(validateHubbleRbac(instance, []*hubblev1alpha1.HubbleRbac{other}), "members may be declared in other HubbleRbacs")
+}
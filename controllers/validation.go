@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+//redshift identifiers must begin with a letter or underscore and may only contain letters, digits, underscores and dollar signs
+var redshiftIdentifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_$]*$`)
+
+const maxRedshiftIdentifierLength = 127
+
+//the session duration limits of an IAM role
+const minSessionDuration = time.Hour
+const maxSessionDuration = 12 * time.Hour
+
+var policyArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::(\d{12}|aws):policy/[\w+=,.@/-]+$`)
+var policyActionPattern = regexp.MustCompile(`^(\*|[a-z0-9-]+:[A-Za-z0-9*]+)$`)
+var policySidPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+var datalakeLocationPattern = regexp.MustCompile(`^s3://[a-z0-9][a-z0-9.-]{1,61}[a-z0-9](/.*)?$`)
+
+func validateRedshiftIdentifier(path *field.Path, value string) *field.Error {
+	if len(value) > maxRedshiftIdentifierLength {
+		return field.TooLong(path, value, maxRedshiftIdentifierLength)
+	}
+	if !redshiftIdentifierPattern.MatchString(value) {
+		return field.Invalid(path, value, "not a valid redshift identifier, must start with a letter or underscore and contain only letters, digits, underscores and dollar signs")
+	}
+	return nil
+}
+
+func validatePolicyStatement(path *field.Path, statement hubblev1alpha1.PolicyStatement) field.ErrorList {
+	var errs field.ErrorList
+	if statement.Effect != "Allow" && statement.Effect != "Deny" {
+		errs = append(errs, field.NotSupported(path.Child("effect"), statement.Effect, []string{"Allow", "Deny"}))
+	}
+	if statement.Sid != "" && !policySidPattern.MatchString(statement.Sid) {
+		errs = append(errs, field.Invalid(path.Child("sid"), statement.Sid, "must contain only letters and digits"))
+	}
+	if len(statement.Actions) == 0 {
+		errs = append(errs, field.Required(path.Child("actions"), "at least one action must be specified"))
+	}
+	for i, action := range statement.Actions {
+		if !policyActionPattern.MatchString(action) {
+			errs = append(errs, field.Invalid(path.Child("actions").Index(i), action, "not a valid IAM action, must be * or of the form service:action"))
+		}
+	}
+	if len(statement.Resources) == 0 {
+		errs = append(errs, field.Required(path.Child("resources"), "at least one resource must be specified"))
+	}
+	for i, resource := range statement.Resources {
+		if resource != "*" && !strings.HasPrefix(resource, "arn:") {
+			errs = append(errs, field.Invalid(path.Child("resources").Index(i), resource, "not a valid resource, must be * or an ARN"))
+		}
+	}
+	return errs
+}
+
+func validateEmail(path *field.Path, value string) *field.Error {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return field.Invalid(path, value, "not a valid email address")
+	}
+	return nil
+}
+
+//Duplicates are detected case insensitively because redshift lowercases all identifiers,
+//while references are resolved by exact name just like buildHubbleModel does.
+type nameSet struct {
+	names      map[string]bool
+	lowercased map[string]bool
+}
+
+func newNameSet() *nameSet {
+	return &nameSet{names: make(map[string]bool), lowercased: make(map[string]bool)}
+}
+
+func (s *nameSet) declare(path *field.Path, name string) *field.Error {
+	key := strings.ToLower(name)
+	if s.lowercased[key] {
+		return field.Duplicate(path, name)
+	}
+	s.lowercased[key] = true
+	s.names[name] = true
+	return nil
+}
+
+//Declares a name from another HubbleRbac. It can be referenced but is not considered a duplicate,
+//conflicting definitions across HubbleRbacs are detected when they are merged.
+func (s *nameSet) declareExternal(name string) {
+	s.names[name] = true
+}
+
+func (s *nameSet) contains(name string) bool {
+	return s.names[name]
+}
+
+func appendIfNotNil(errs field.ErrorList, err *field.Error) field.ErrorList {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+// validateHubbleRbac collects every problem with the spec instead of stopping at the first one,
+// so the user can fix all of them in one go.
+// The other HubbleRbacs are the ones the spec will be merged with, its roles may reference their databases, policies and roles,
+// and its teams may include their users.
+func validateHubbleRbac(instance *hubblev1alpha1.HubbleRbac, others []*hubblev1alpha1.HubbleRbac) field.ErrorList {
+	var errs field.ErrorList
+	spec := instance.Spec
+	specPath := field.NewPath("spec")
+
+	databases := newNameSet()
+	devDatabases := newNameSet()
+	policies := newNameSet()
+	roles := newNameSet()
+
+	for _, other := range others {
+		for _, database := range other.Spec.Databases {
+			databases.declareExternal(database.Name)
+		}
+		for _, database := range other.Spec.DevDatabases {
+			devDatabases.declareExternal(database.Name)
+		}
+		for _, policy := range other.Spec.Policies {
+			policies.declareExternal(policy.Name)
+		}
+		for _, role := range other.Spec.Roles {
+			roles.declareExternal(role.Name)
+		}
+	}
+
+	for i, database := range spec.Databases {
+		path := specPath.Child("databases").Index(i)
+		errs = appendIfNotNil(errs, databases.declare(path.Child("name"), database.Name))
+		errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("database"), database.Database))
+		if database.Cluster == "" {
+			errs = append(errs, field.Required(path.Child("cluster"), "cluster must be specified"))
+		}
+	}
+
+	for i, database := range spec.DevDatabases {
+		path := specPath.Child("devDatabases").Index(i)
+		errs = appendIfNotNil(errs, devDatabases.declare(path.Child("name"), database.Name))
+		if database.Cluster == "" {
+			errs = append(errs, field.Required(path.Child("cluster"), "cluster must be specified"))
+		}
+	}
+
+	for i, policy := range spec.Policies {
+		path := specPath.Child("policies").Index(i)
+		errs = appendIfNotNil(errs, policies.declare(path.Child("name"), policy.Name))
+		if !policyArnPattern.MatchString(policy.Arn) {
+			errs = append(errs, field.Invalid(path.Child("arn"), policy.Arn, "not a valid IAM policy ARN"))
+		}
+	}
+
+	datalakes := newNameSet()
+	for i, datalake := range spec.Datalakes {
+		path := specPath.Child("datalakes").Index(i)
+		errs = appendIfNotNil(errs, datalakes.declare(path.Child("name"), datalake.Name))
+		if !datalakeLocationPattern.MatchString(datalake.Location) {
+			errs = append(errs, field.Invalid(path.Child("location"), datalake.Location, "not a valid S3 location, must be of the form s3://bucket/prefix"))
+		}
+	}
+
+	for i, role := range spec.Roles {
+		path := specPath.Child("roles").Index(i)
+		errs = appendIfNotNil(errs, roles.declare(path.Child("name"), role.Name))
+		errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("name"), role.Name))
+
+		for j, name := range role.Databases {
+			if !databases.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("databases").Index(j), name))
+			}
+		}
+		for j, name := range role.DevDatabases {
+			if !devDatabases.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("devDatabases").Index(j), name))
+			}
+		}
+		for j, name := range role.Policies {
+			if !policies.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("policies").Index(j), name))
+			}
+		}
+		for j, name := range role.DatawarehouseGrants {
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("datawarehouseGrants").Index(j), name))
+		}
+		for j, name := range role.DatalakeGrants {
+			//the external schema is named after the glue database without dashes
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("datalakeGrants").Index(j), strings.ReplaceAll(name, "-", "")))
+		}
+		if role.SessionDuration != nil && (role.SessionDuration.Duration < minSessionDuration || role.SessionDuration.Duration > maxSessionDuration) {
+			errs = append(errs, field.Invalid(path.Child("sessionDuration"), role.SessionDuration.Duration.String(), "must be between 1h and 12h"))
+		}
+		tables := newNameSet()
+		for j, grant := range role.TableGrants {
+			grantPath := path.Child("tableGrants").Index(j)
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(grantPath.Child("schema"), grant.Schema))
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(grantPath.Child("table"), grant.Table))
+			errs = appendIfNotNil(errs, tables.declare(grantPath.Child("table"), fmt.Sprintf("%s.%s", grant.Schema, grant.Table)))
+			for k, column := range grant.Columns {
+				errs = appendIfNotNil(errs, validateRedshiftIdentifier(grantPath.Child("columns").Index(k), column))
+			}
+		}
+		for j, statement := range role.PolicyStatements {
+			errs = append(errs, validatePolicyStatement(path.Child("policyStatements").Index(j), statement)...)
+		}
+		granted := make(map[string]bool)
+		for _, name := range role.DatawarehouseGrants {
+			granted[name] = true
+		}
+		var schemas []string
+		for schema := range role.Privileges {
+			schemas = append(schemas, schema)
+		}
+		sort.Strings(schemas)
+		for _, schema := range schemas {
+			level := role.Privileges[schema]
+			if _, ok := privilegeRanks[level]; !ok {
+				errs = append(errs, field.NotSupported(path.Child("privileges").Key(schema), level, []string{"read", "write", "owner"}))
+			}
+			if !granted[schema] {
+				errs = append(errs, field.Invalid(path.Child("privileges").Key(schema), level, "the schema must be one of the datawarehouseGrants of the role"))
+			}
+		}
+	}
+
+	//roles may extend roles declared after them, so the references are checked once all roles are declared
+	for i, role := range spec.Roles {
+		for j, name := range role.Extends {
+			if !roles.contains(name) {
+				errs = append(errs, field.NotFound(specPath.Child("roles").Index(i).Child("extends").Index(j), name))
+			}
+		}
+	}
+
+	users := newNameSet()
+	emails := newNameSet()
+	for _, other := range others {
+		for _, user := range other.Spec.Users {
+			users.declareExternal(user.Name)
+		}
+	}
+	for i, user := range spec.Users {
+		path := specPath.Child("users").Index(i)
+		errs = appendIfNotNil(errs, users.declare(path.Child("name"), user.Name))
+		errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("name"), user.Name))
+		errs = appendIfNotNil(errs, emails.declare(path.Child("email"), user.Email))
+		errs = appendIfNotNil(errs, validateEmail(path.Child("email"), user.Email))
+
+		for j, name := range user.Roles {
+			if !roles.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("roles").Index(j), name))
+				continue
+			}
+			//a redshift user named <user>_<role> is created for every role assignment
+			username := fmt.Sprintf("%s_%s", user.Name, name)
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(path.Child("roles").Index(j), username))
+		}
+
+		for j, assignment := range user.RoleAssignments {
+			assignmentPath := path.Child("roleAssignments").Index(j)
+			if !roles.contains(assignment.Role) {
+				errs = append(errs, field.NotFound(assignmentPath.Child("role"), assignment.Role))
+				continue
+			}
+			if assignment.ValidFrom != nil && assignment.ExpiresAt != nil && !assignment.ValidFrom.Before(assignment.ExpiresAt) {
+				errs = append(errs, field.Invalid(assignmentPath.Child("expiresAt"), assignment.ExpiresAt.String(), "must be after validFrom"))
+			}
+			username := fmt.Sprintf("%s_%s", user.Name, assignment.Role)
+			errs = appendIfNotNil(errs, validateRedshiftIdentifier(assignmentPath.Child("role"), username))
+		}
+	}
+
+	teams := newNameSet()
+	for i, team := range spec.Teams {
+		path := specPath.Child("teams").Index(i)
+		errs = appendIfNotNil(errs, teams.declare(path.Child("name"), team.Name))
+
+		for j, name := range team.Members {
+			if !users.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("members").Index(j), name))
+			}
+		}
+		for j, name := range team.Roles {
+			if !roles.contains(name) {
+				errs = append(errs, field.NotFound(path.Child("roles").Index(j), name))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	merged, err := mergeHubbleRbacs(append(others, instance))
+	if err != nil {
+		return append(errs, field.Invalid(specPath, instance.Name, err.Error()))
+	}
+
+	//run the same mapping as the controller to catch anything the checks above have missed
+	if _, err := buildHubbleModel(merged, time.Now()); err != nil {
+		errs = append(errs, field.Invalid(specPath, instance.Name, err.Error()))
+	}
+
+	return errs
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validSpec() *hubblev1alpha1.HubbleRbac {
+	return &hubblev1alpha1.HubbleRbac{
+		Spec: hubblev1alpha1.HubbleRbacSpec{
+			Policies: []hubblev1alpha1.PolicyReference{
+				{Name: "access-to-secrets", Arn: "arn:aws:iam::478824949770:policy/AllowGetHubbleSecrets-prod"},
+			},
+			Databases: []hubblev1alpha1.Database{
+				{Name: "unstable", Cluster: "hubble-unstable", Database: "prod"},
+			},
+			Roles: []hubblev1alpha1.Role{
+				{
+					Name:                "BiAnalyst",
+					Databases:           []string{"unstable"},
+					DatawarehouseGrants: []string{"public", "public_bi"},
+					DatalakeGrants:      []string{"lw-go-events"},
+					Policies:            []string{"access-to-secrets"},
+				},
+			},
+			Users: []hubblev1alpha1.User{
+				{Name: "jwr", Email: "jwr@lunar.app", Roles: []string{"BiAnalyst"}},
+			},
+		},
+	}
+}
+
+func Test_Validate_OnlySpecChangesAreValidated(t *testing.T) {
+
+	assert := assert.New(t)
+
+	old := validSpec()
+	annotated := old.DeepCopy()
+	annotated.Annotations = map[string]string{hubblev1alpha1.ApprovedPlanAnnotation: "9b1f2c3d4e5f6a7b"}
+	changed := old.DeepCopy()
+	changed.Spec.Users = nil
+	deleted := changed.DeepCopy()
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	assert.True(specChanged(nil, old), "created HubbleRbacs are validated")
+	assert.True(specChanged(old, changed))
+	assert.False(specChanged(old, annotated), "changes to the metadata are allowed")
+	assert.False(specChanged(old, deleted), "HubbleRbacs that are being deleted are not validated, so their finalizer can be removed")
+}
+
+func Test_Validate_ValidSpec(t *testing.T) {
+
+	assert := assert.New(t)
+
+	errs := validateHubbleRbac(validSpec(), nil)
+
+	assert.Empty(errs)
+}
+
+func Test_Validate_ReportsAllProblems(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles[0].Databases = []string{"unstable", "missing"}
+	instance.Spec.Roles[0].Policies = []string{"missing"}
+	instance.Spec.Policies[0].Arn = "not-an-arn"
+	instance.Spec.Users = append(instance.Spec.Users,
+		hubblev1alpha1.User{Name: "JWR", Email: "jwr@lunar.app", Roles: []string{"NoSuchRole"}},
+		hubblev1alpha1.User{Name: "drop table", Email: "not an email"},
+	)
+
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+
+	assert.Contains(fields, "spec.roles[0].databases[1]", "missing database is reported")
+	assert.Contains(fields, "spec.roles[0].policies[0]", "missing policy is reported")
+	assert.Contains(fields, "spec.policies[0].arn", "malformed policy ARN is reported")
+	assert.Contains(fields, "spec.users[1].name", "duplicate user name is reported")
+	assert.Contains(fields, "spec.users[1].email", "duplicate email is reported")
+	assert.Contains(fields, "spec.users[1].roles[0]", "missing role is reported")
+	assert.Contains(fields, "spec.users[2].name", "invalid redshift identifier is reported")
+	assert.Contains(fields, "spec.users[2].email", "malformed email is reported")
+}
+
+func Test_Validate_DuplicateRolesAndDatabases(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles = append(instance.Spec.Roles, hubblev1alpha1.Role{Name: "bianalyst"})
+	instance.Spec.Databases = append(instance.Spec.Databases, hubblev1alpha1.Database{Name: "unstable", Cluster: "hubble", Database: "prod"})
+
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+
+	assert.Contains(fields, "spec.roles[1].name")
+	assert.Contains(fields, "spec.databases[1].name")
+}
+
+func Test_Validate_ReferencesAcrossHubbleRbacs(t *testing.T) {
+
+	assert := assert.New(t)
+
+	platform := validSpec()
+	platform.Name = "platform"
+
+	credit := &hubblev1alpha1.HubbleRbac{
+		Spec: hubblev1alpha1.HubbleRbacSpec{
+			Roles: []hubblev1alpha1.Role{{Name: "CreditAnalyst", Databases: []string{"unstable"}}},
+			Users: []hubblev1alpha1.User{{Name: "nra", Email: "nra@lunar.app", Roles: []string{"CreditAnalyst", "BiAnalyst"}}},
+		},
+	}
+
+	assert.Empty(validateHubbleRbac(credit, []*hubblev1alpha1.HubbleRbac{platform}), "databases and roles of other HubbleRbacs can be referenced")
+	assert.NotEmpty(validateHubbleRbac(credit, nil), "references are not resolved without the other HubbleRbacs")
+
+	credit.Spec.Roles = append(credit.Spec.Roles, hubblev1alpha1.Role{Name: "BiAnalyst"})
+	assert.NotEmpty(validateHubbleRbac(credit, []*hubblev1alpha1.HubbleRbac{platform}), "conflicting definitions are rejected")
+}
+
+func Test_Validate_Privileges(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles[0].Privileges = map[string]hubblev1alpha1.PrivilegeLevel{"public_bi": "write"}
+	assert.Empty(validateHubbleRbac(instance, nil))
+
+	instance.Spec.Roles[0].Privileges = map[string]hubblev1alpha1.PrivilegeLevel{"public_bi": "admin", "credit": "read"}
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal([]string{"spec.roles[0].privileges[credit]", "spec.roles[0].privileges[public_bi]"}, fields)
+}
+
+func Test_Validate_TableGrants(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles[0].TableGrants = []hubblev1alpha1.TableGrant{{Schema: "pii", Table: "customers", Columns: []string{"id", "name"}}}
+	assert.Empty(validateHubbleRbac(instance, nil))
+
+	instance.Spec.Roles[0].TableGrants = append(instance.Spec.Roles[0].TableGrants,
+		hubblev1alpha1.TableGrant{Schema: "PII", Table: "Customers"},
+		hubblev1alpha1.TableGrant{Schema: "pii", Table: "orders", Columns: []string{"drop table"}},
+	)
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal([]string{"spec.roles[0].tableGrants[1].table", "spec.roles[0].tableGrants[2].columns[0]"}, fields)
+}
+
+func Test_Validate_SessionDuration(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles[0].SessionDuration = &metav1.Duration{Duration: time.Hour}
+	assert.Empty(validateHubbleRbac(instance, nil))
+
+	instance.Spec.Roles[0].SessionDuration = &metav1.Duration{Duration: 15 * time.Minute}
+	errs := validateHubbleRbac(instance, nil)
+	assert.Len(errs, 1)
+	assert.Equal("spec.roles[0].sessionDuration", errs[0].Field)
+
+	instance.Spec.Roles[0].SessionDuration = &metav1.Duration{Duration: 24 * time.Hour}
+	assert.Len(validateHubbleRbac(instance, nil), 1)
+}
+
+func Test_Validate_PolicyStatements(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Roles[0].PolicyStatements = []hubblev1alpha1.PolicyStatement{
+		{Sid: "ReadReports", Effect: "Allow", Actions: []string{"s3:GetObject", "s3:List*"}, Resources: []string{"arn:aws:s3:::lunar-reports/*"}},
+	}
+	assert.Empty(validateHubbleRbac(instance, nil))
+
+	instance.Spec.Roles[0].PolicyStatements = append(instance.Spec.Roles[0].PolicyStatements,
+		hubblev1alpha1.PolicyStatement{Effect: "Permit", Actions: []string{"GetObject"}, Resources: []string{"*"}},
+		hubblev1alpha1.PolicyStatement{Sid: "read-reports", Effect: "Deny", Resources: []string{"lunar-reports"}},
+	)
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal([]string{
+		"spec.roles[0].policyStatements[1].effect",
+		"spec.roles[0].policyStatements[1].actions[0]",
+		"spec.roles[0].policyStatements[2].sid",
+		"spec.roles[0].policyStatements[2].actions",
+		"spec.roles[0].policyStatements[2].resources[0]",
+	}, fields)
+}
+
+func Test_Validate_Datalakes(t *testing.T) {
+
+	assert := assert.New(t)
+
+	instance := validSpec()
+	instance.Spec.Datalakes = []hubblev1alpha1.Datalake{{Name: "lw-go-events", Location: "s3://lunar-datalake/events"}}
+	assert.Empty(validateHubbleRbac(instance, nil))
+
+	instance.Spec.Datalakes = append(instance.Spec.Datalakes,
+		hubblev1alpha1.Datalake{Name: "lw-go-events", Location: "s3://lunar-datalake/go-events"},
+		hubblev1alpha1.Datalake{Name: "lw-events", Location: "lunar-datalake/events"},
+	)
+	errs := validateHubbleRbac(instance, nil)
+
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	assert.Equal([]string{"spec.datalakes[1].name", "spec.datalakes[2].location"}, fields)
+}
@@ -1,12 +1,18 @@
 package google
 
+import "time"
+
 type User struct {
 	Email string
 	Roles map[string]bool
+	//The duration of the sessions of the user. Google has a single session duration per user,
+	//so it can be no longer than the shortest session duration of the roles assigned to the user.
+	SessionDuration time.Duration
 }
 
 type Model struct {
-	Users []*User
+	IdentityProvider string //the name of the SAML provider in IAM that the users log in through
+	Users            []*User
 }
 
 func (m *Model) LookupUser(email string) *User {
@@ -1,8 +1,27 @@
 package hubble
 
+import "time"
+
 //An identifier for a group of related tables. In redshift this corresponds to a schema.
 type DataSet string
 
+//Access to a single table of a data set, or to some of its columns if columns are given.
+//Used for sensitive data where only parts of a data set may be accessed.
+type TableGrant struct {
+	DataSet DataSet
+	Table   string
+	Columns []string
+}
+
+//The level of access a role has to a data set.
+type Privilege string
+
+const (
+	ReadPrivilege  Privilege = "read"  //query the data set
+	WritePrivilege Privilege = "write" //query, create and modify tables in the data set
+	OwnerPrivilege Privilege = "owner" //write plus referencing its tables from foreign keys
+)
+
 type Database struct {
 	ClusterIdentifier string //the identifier of the cluster on which the database resides
 	Name              string
@@ -20,6 +39,7 @@ type DevDatabase struct {
 type GlueDatabase struct {
 	ShortName string //the name of the external schema in redshift
 	Name      string //the name of database in AWS Glue
+	Location  string //the S3 location of the data, e.g. s3://datalake/events. The configured default location is used if it is empty.
 }
 
 //TODO: as we should keep the hubble model technology agnostic we should remove the "Glue" part of this type name.
@@ -32,12 +52,24 @@ type User struct {
 
 //If a user is assigned a role it can log into that role from the terminal and access the granted resources.
 type Role struct {
-	Name                 string             //the name of the role
-	GrantedDatabases     []*Database        //the set of databases this user has access to
-	GrantedDevDatabases  []*DevDatabase     //the set of dev databases that this user has access to
-	GrantedGlueDatabases []*GlueDatabase    //the set of glue databases this user has access to
-	Acl                  []DataSet          //the set of data groups this user has access to. E.g. a credit analyst should only have access to credit related data.
-	Policies             []*PolicyReference //the set of extra IAM policies this user has access to. Those could be policies required by the CLI's that are part of the analyst tool chain.
+	Name                 string                //the name of the role
+	GrantedDatabases     []*Database           //the set of databases this user has access to
+	GrantedDevDatabases  []*DevDatabase        //the set of dev databases that this user has access to
+	GrantedGlueDatabases []*GlueDatabase       //the set of glue databases this user has access to
+	Acl                  []DataSet             //the set of data groups this user has access to. E.g. a credit analyst should only have access to credit related data.
+	Privileges           map[DataSet]Privilege //the level of access to the data sets in the acl, a data set that is not present can only be read.
+	TableGrants          []TableGrant          //the tables this user can read without having access to the rest of the data set.
+	SessionDuration      time.Duration         //the maximum duration of a session of the role, the default of the identity provider is used if it is zero
+	Policies             []*PolicyReference    //the set of extra IAM policies this user has access to. Those could be policies required by the CLI's that are part of the analyst tool chain.
+	PolicyStatements     []PolicyStatement     //extra permissions of the role that are not covered by the referenced policies.
+}
+
+//Extra permissions given to a role without referencing an existing IAM policy.
+type PolicyStatement struct {
+	Sid       string
+	Effect    string //Allow or Deny
+	Actions   []string
+	Resources []string
 }
 
 //the complete Hubble model which contains all the resources that are managed by the controller.
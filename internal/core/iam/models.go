@@ -1,5 +1,25 @@
 package iam
 
+import "time"
+
+//The SAML identity provider that users log into the roles through.
+type IdentityProvider struct {
+	Name                   string        //the name of the SAML provider in IAM
+	DefaultSessionDuration time.Duration //the session duration of roles that do not set their own
+}
+
+func DefaultIdentityProvider() IdentityProvider {
+	return IdentityProvider{Name: "GoogleApps", DefaultSessionDuration: 4 * time.Hour}
+}
+
+//Where the data of the glue databases is stored and queried, used to give roles access to the data lake from Athena.
+type DatalakeAccess struct {
+	AccountId        string //the account of the glue catalog and Athena workgroup, any account if not set
+	Region           string //any region if not set
+	AthenaWorkgroup  string //the workgroup queries are run in, primary if not set
+	LocationTemplate string //the S3 location of glue databases that do not declare their own, e.g. s3://datalake/%s where %s is the name of the glue database
+}
+
 //This represents an IAM policy that allows a user to log into a set of databases using the specified database username.
 type DatabaseLoginPolicy struct {
 	Email            string
@@ -17,11 +37,22 @@ type PolicyReference struct {
 	Arn string
 }
 
+//A statement of a policy that is managed by the controller and only attached to the role it is declared on.
+type PolicyStatement struct {
+	Sid       string //an optional identifier of the statement
+	Effect    string //Allow or Deny
+	Actions   []string
+	Resources []string
+}
+
 //This represents an IAM role that references the set of IAM policies.
 type AwsRole struct {
 	Name                  string
+	IdentityProvider      string        //the name of the SAML provider that is trusted to log into the role
+	SessionDuration       time.Duration //the maximum duration of a session of the role
 	DatabaseLoginPolicies []*DatabaseLoginPolicy
 	Policies              []*PolicyReference
+	Statements            []*PolicyStatement //rendered into a single managed policy attached to the role
 }
 
 //The complete IAM model consists of a set of managed IAM roles
@@ -4,13 +4,26 @@ import (
 	"github.com/go-logr/logr"
 )
 
+//DagRunner runs the tasks of a DAG, skipping the tasks that depend on a task that failed or was skipped.
+type DagRunner interface {
+	Run(dag *ReconciliationDag)
+}
+
+//TaskListener is told about every task that has been run or skipped, the error is nil unless the task failed.
+type TaskListener interface {
+	TaskFinished(task *Task, err error)
+}
+
 type SequentialDagRunner struct {
 	taskRunner TaskRunner
+	listener   TaskListener
+	retries    RetryPolicy
 	logger     logr.Logger
 }
 
-func NewSequentialDagRunner(taskRunner TaskRunner, logger logr.Logger) *SequentialDagRunner {
-	return &SequentialDagRunner{taskRunner: taskRunner, logger: logger}
+//The listener is optional.
+func NewSequentialDagRunner(taskRunner TaskRunner, listener TaskListener, retries RetryPolicy, logger logr.Logger) *SequentialDagRunner {
+	return &SequentialDagRunner{taskRunner: taskRunner, listener: listener, retries: retries, logger: logger}
 }
 
 func (d *SequentialDagRunner) Run(dag *ReconciliationDag) {
@@ -20,18 +33,109 @@ func (d *SequentialDagRunner) Run(dag *ReconciliationDag) {
 			if task.CannotRun() {
 				d.logger.Info("skipping task", "task", task.String())
 				task.Skip()
+				if d.listener != nil {
+					d.listener.TaskFinished(task, nil)
+				}
 				continue
 			}
 			task.Start()
-			err := ExecuteTask(d.taskRunner, task)
+			err := executeWithRetries(d.taskRunner, task, d.retries)
 			if err != nil {
 				task.Failed()
-				d.logger.Error(err, "task failed", "task", task.String())
-				continue
+				d.logger.Error(err, "task failed", "task", task.String(), "attempts", task.Attempts())
+			} else {
+				task.Success()
+			}
+			if d.listener != nil {
+				d.listener.TaskFinished(task, err)
 			}
-			task.Success()
 		}
 	}
 }
 
-//TODO: implement a DAG runner that can parallelize the execution
+//ParallelDagRunner runs every task whose upstream tasks are done as soon as a worker is available.
+//The number of tasks running against the same cluster can be capped to limit the number of connections to it.
+type ParallelDagRunner struct {
+	taskRunner    TaskRunner
+	listener      TaskListener
+	workers       int
+	maxPerCluster int
+	retries       RetryPolicy
+	logger        logr.Logger
+}
+
+type taskResult struct {
+	task *Task
+	err  error
+}
+
+//The task runner must be safe for concurrent use, the listener is optional and only called from the goroutine calling Run.
+//A maxPerCluster of zero doesn't limit the tasks running against a cluster.
+func NewParallelDagRunner(taskRunner TaskRunner, listener TaskListener, workers int, maxPerCluster int, retries RetryPolicy, logger logr.Logger) *ParallelDagRunner {
+	if workers < 1 {
+		workers = 1
+	}
+	return &ParallelDagRunner{taskRunner: taskRunner, listener: listener, workers: workers, maxPerCluster: maxPerCluster, retries: retries, logger: logger}
+}
+
+func (d *ParallelDagRunner) finished(task *Task, err error) {
+	if d.listener != nil {
+		d.listener.TaskFinished(task, err)
+	}
+}
+
+func (d *ParallelDagRunner) Run(dag *ReconciliationDag) {
+
+	results := make(chan taskResult)
+	running := 0
+	runningPerCluster := make(map[string]int)
+
+	for {
+		skipped := false
+
+		for _, task := range dag.GetWaiting() {
+			if task.CannotRun() {
+				d.logger.Info("skipping task", "task", task.String())
+				task.Skip()
+				d.finished(task, nil)
+				skipped = true
+				continue
+			}
+			if running == d.workers {
+				break
+			}
+			clusterIdentifier, _ := task.Location()
+			if d.maxPerCluster > 0 && runningPerCluster[clusterIdentifier] == d.maxPerCluster {
+				continue
+			}
+
+			task.Start()
+			running++
+			runningPerCluster[clusterIdentifier]++
+
+			go func(task *Task) {
+				results <- taskResult{task: task, err: executeWithRetries(d.taskRunner, task, d.retries)}
+			}(task)
+		}
+
+		if skipped {
+			continue //the tasks downstream of the skipped tasks may be waiting now
+		}
+		if running == 0 {
+			return
+		}
+
+		result := <-results
+		running--
+		clusterIdentifier, _ := result.task.Location()
+		runningPerCluster[clusterIdentifier]--
+
+		if result.err != nil {
+			result.task.Failed()
+			d.logger.Error(result.err, "task failed", "task", result.task.String(), "attempts", result.task.Attempts())
+		} else {
+			result.task.Success()
+		}
+		d.finished(result.task, result.err)
+	}
+}
@@ -0,0 +1,201 @@
+package redshift
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("connection reset")
+
+//Records how many tasks run against each cluster at the same time, failing the tasks on the groups named failingGroup.
+//The failures are transient if transientFailures is set, the tasks then succeed after failing that many times.
+type concurrencyRecorder struct {
+	failingGroup      string
+	transientFailures int
+	lock              sync.Mutex
+	running           map[string]int
+	maxRunning        map[string]int
+	failures          int
+}
+
+func newConcurrencyRecorder(failingGroup string) *concurrencyRecorder {
+	return &concurrencyRecorder{failingGroup: failingGroup, running: make(map[string]int), maxRunning: make(map[string]int)}
+}
+
+func (r *concurrencyRecorder) execute(clusterIdentifier string, groupName string) error {
+	r.lock.Lock()
+	r.running[clusterIdentifier]++
+	if r.running[clusterIdentifier] > r.maxRunning[clusterIdentifier] {
+		r.maxRunning[clusterIdentifier] = r.running[clusterIdentifier]
+	}
+	r.lock.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.running[clusterIdentifier]--
+
+	if groupName != "" && groupName == r.failingGroup {
+		if r.transientFailures == 0 {
+			return fmt.Errorf("unable to create group %s", groupName)
+		}
+		if r.failures < r.transientFailures {
+			r.failures++
+			return fmt.Errorf("unable to create group %s: %w", groupName, errTransient)
+		}
+	}
+	return nil
+}
+
+func (r *concurrencyRecorder) CreateUser(model *UserModel) error {
+	return r.execute(model.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) DropUser(model *UserModel) error {
+	return r.execute(model.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) CreateGroup(model *GroupModel) error {
+	return r.execute(model.ClusterIdentifier, model.Group.Name)
+}
+func (r *concurrencyRecorder) DropGroup(model *GroupModel) error {
+	return r.execute(model.ClusterIdentifier, model.Group.Name)
+}
+func (r *concurrencyRecorder) CreateSchema(model *SchemaModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) CreateExternalSchema(model *ExternalSchemaModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) CreateDatabase(model *DatabaseModel) error {
+	return r.execute(model.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) GrantAccess(model *GrantsModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) RevokeAccess(model *GrantsModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) AddToGroup(model *MembershipModel) error {
+	return r.execute(model.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) RemoveFromGroup(model *MembershipModel) error {
+	return r.execute(model.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) GrantTableAccess(model *TableGrantsModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+func (r *concurrencyRecorder) RevokeTableAccess(model *TableGrantsModel) error {
+	return r.execute(model.Database.ClusterIdentifier, "")
+}
+
+//Two clusters with a group and four members each.
+func buildDagOnTwoClusters() *ReconciliationDag {
+	current := Model{}
+	current.DeclareCluster("dev")
+	current.DeclareCluster("prod")
+
+	desired := Model{}
+	for _, clusterIdentifier := range []string{"dev", "prod"} {
+		cluster := desired.DeclareCluster(clusterIdentifier)
+		group := cluster.DeclareGroup(clusterIdentifier + "analyst")
+		for i := 0; i < 4; i++ {
+			cluster.DeclareUser(fmt.Sprintf("user%d_%s", i, group.Name), group)
+		}
+	}
+	return Reconcile(&current, &desired, DefaultReconcilerConfig())
+}
+
+func countStates(dag *ReconciliationDag) map[TaskState]int {
+	result := make(map[TaskState]int)
+	for _, task := range dag.tasks {
+		result[task.State()]++
+	}
+	return result
+}
+
+func Test_ParallelDagRunner(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sequentialDag := buildDagOnTwoClusters()
+	NewSequentialDagRunner(newConcurrencyRecorder("prodanalyst"), nil, RetryPolicy{}, logrtesting.NullLogger{}).Run(sequentialDag)
+
+	recorder := newConcurrencyRecorder("prodanalyst")
+	parallelDag := buildDagOnTwoClusters()
+	NewParallelDagRunner(recorder, nil, 8, 2, RetryPolicy{}, logrtesting.NullLogger{}).Run(parallelDag)
+
+	assert.Equal(countStates(sequentialDag), countStates(parallelDag), "the tasks end in the same states as when they are run one at a time")
+	assert.Equal(1, countStates(parallelDag)[Failed])
+	assert.Equal(4, countStates(parallelDag)[Skipped], "the members are not added to the group that failed")
+	assert.False(parallelDag.PendingExists())
+
+	assert.Equal(2, recorder.maxRunning["dev"], "tasks are run in parallel up to the cap of the cluster")
+	assert.Equal(2, recorder.maxRunning["prod"])
+}
+
+func Test_ParallelDagRunner_Workers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	recorder := newConcurrencyRecorder("")
+	dag := buildDagOnTwoClusters()
+	NewParallelDagRunner(recorder, nil, 1, 0, RetryPolicy{}, logrtesting.NullLogger{}).Run(dag)
+
+	assert.Equal(map[TaskState]int{Success: dag.NumTasks()}, countStates(dag))
+	assert.Equal(1, recorder.maxRunning["dev"], "a single worker runs one task at a time")
+	assert.Equal(1, recorder.maxRunning["prod"])
+}
+
+func isTransient(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func Test_Retries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	retries := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Retryable: isTransient}
+
+	recorder := newConcurrencyRecorder("devanalyst")
+	recorder.transientFailures = 2
+	dag := buildDagOnTwoClusters()
+	NewSequentialDagRunner(recorder, nil, retries, logrtesting.NullLogger{}).Run(dag)
+
+	assert.Equal(DagResult{Succeeded: dag.NumTasks(), Retried: 1, Attempts: dag.NumTasks() + 2}, dag.Result(), "the task succeeds on the last attempt")
+
+	recorder = newConcurrencyRecorder("devanalyst")
+	recorder.transientFailures = 3
+	dag = buildDagOnTwoClusters()
+	NewParallelDagRunner(recorder, nil, 4, 0, retries, logrtesting.NullLogger{}).Run(dag)
+
+	assert.Equal(DagResult{Succeeded: dag.NumTasks() - 5, Failed: 1, Skipped: 4, Retried: 1, Attempts: dag.NumTasks() - 4 + 2}, dag.Result(), "the task fails when it runs out of attempts")
+
+	recorder = newConcurrencyRecorder("devanalyst")
+	dag = buildDagOnTwoClusters()
+	NewSequentialDagRunner(recorder, nil, retries, logrtesting.NullLogger{}).Run(dag)
+
+	assert.Equal(0, dag.Result().Retried, "permanent errors are not retried")
+	assert.Equal(1, dag.Result().Failed)
+}
+
+func Test_RetryPolicy_Backoff(t *testing.T) {
+
+	assert := assert.New(t)
+
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	assert.Equal(time.Second, policy.backoff(1))
+	assert.Equal(2*time.Second, policy.backoff(2))
+	assert.Equal(4*time.Second, policy.backoff(3))
+	assert.Equal(5*time.Second, policy.backoff(4), "the backoff is capped")
+	assert.Equal(5*time.Second, policy.backoff(100))
+
+	uncapped := RetryPolicy{InitialBackoff: time.Second}
+	assert.Equal(8*time.Second, uncapped.backoff(4))
+}
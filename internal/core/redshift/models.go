@@ -2,12 +2,67 @@ package redshift
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
-//A redshift schema
+//The level of access a group is granted on a schema.
+type Privilege string
+
+const (
+	ReadPrivilege  Privilege = "read"  //USAGE on the schema and SELECT on its tables
+	WritePrivilege Privilege = "write" //read plus CREATE on the schema and INSERT, UPDATE and DELETE on its tables
+	OwnerPrivilege Privilege = "owner" //write plus REFERENCES on its tables
+	//Access granted before privilege levels were introduced: all privileges on the schema and SELECT on its tables.
+	//It is never desired, so it is replaced by the declared level the next time the group is reconciled.
+	LegacyPrivilege Privilege = "legacy"
+)
+
+//Removing CREATE from a legacy grant lowers it, so it ranks with the write level.
+var privilegeRanks = map[Privilege]int{ReadPrivilege: 1, WritePrivilege: 2, LegacyPrivilege: 2, OwnerPrivilege: 3}
+
+//Returns true if the privilege grants at least the same access as the other privilege.
+func (p Privilege) Includes(other Privilege) bool {
+	return privilegeRanks[p.OrDefault()] >= privilegeRanks[other.OrDefault()]
+}
+
+//An empty privilege means read access.
+func (p Privilege) OrDefault() Privilege {
+	if p == "" {
+		return ReadPrivilege
+	}
+	return p
+}
+
+//A redshift schema. When granted to a group, the privilege is the level of access granted on the schema.
 type Schema struct {
-	Name string
+	Name      string
+	Privilege Privilege
+}
+
+//A table that a group is granted SELECT on without being granted access to the rest of the schema.
+//If columns are given, only those columns of the table can be selected.
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []string
+}
+
+func (t *Table) Identifier() string {
+	return fmt.Sprintf("%s.%s", t.Schema, t.Name)
+}
+
+//Returns true if the grants give access to the same columns. No columns means the whole table.
+func (t *Table) SameColumns(other *Table) bool {
+	if len(t.Columns) != len(other.Columns) {
+		return false
+	}
+	for i, column := range t.Columns {
+		if !strings.EqualFold(column, other.Columns[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 //An external schema references a glue database that allows the user to query the S3 data lake.
@@ -42,6 +97,7 @@ type DatabaseGroup struct {
 	Name                   string
 	GrantedSchemas         []*Schema
 	GrantedExternalSchemas []*ExternalSchema
+	GrantedTables          []*Table
 }
 
 //a redshift database with the given name that resides on the given cluster
@@ -227,10 +283,13 @@ func (d *Database) Identifier() string {
 	return fmt.Sprintf("%s/%s", d.ClusterIdentifier, d.Name)
 }
 
+//If the schema has already been granted, the highest of the two privileges is granted.
 func (g *DatabaseGroup) GrantSchema(schema *Schema) {
 	existing := g.LookupGrantedSchema(schema.Name)
 	if existing == nil {
 		g.GrantedSchemas = append(g.GrantedSchemas, schema)
+	} else if !existing.Privilege.Includes(schema.Privilege) {
+		existing.Privilege = schema.Privilege
 	}
 }
 
@@ -241,6 +300,49 @@ func (g *DatabaseGroup) GrantExternalSchema(schema *ExternalSchema) {
 	}
 }
 
+//If the table has already been granted, the grant is extended with the given columns.
+//Granting the whole table is broader than granting any of its columns, so the columns are dropped if either grant has none.
+func (g *DatabaseGroup) GrantTable(table *Table) {
+	existing := g.LookupGrantedTable(table.Schema, table.Name)
+	if existing == nil {
+		granted := &Table{Schema: strings.ToLower(table.Schema), Name: strings.ToLower(table.Name)}
+		for _, column := range table.Columns {
+			granted.Columns = append(granted.Columns, strings.ToLower(column))
+		}
+		sort.Strings(granted.Columns)
+		g.GrantedTables = append(g.GrantedTables, granted)
+		return
+	}
+	if len(existing.Columns) == 0 {
+		return
+	}
+	if len(table.Columns) == 0 {
+		existing.Columns = nil
+		return
+	}
+	for _, column := range table.Columns {
+		found := false
+		for _, c := range existing.Columns {
+			if strings.EqualFold(c, column) {
+				found = true
+			}
+		}
+		if !found {
+			existing.Columns = append(existing.Columns, strings.ToLower(column))
+		}
+	}
+	sort.Strings(existing.Columns)
+}
+
+func (g *DatabaseGroup) LookupGrantedTable(schema string, name string) *Table {
+	for _, table := range g.GrantedTables {
+		if strings.EqualFold(table.Schema, schema) && strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
 func (g *DatabaseGroup) Granted() []string {
 	schemas := make([]string, 0, len(g.GrantedSchemas)+len(g.GrantedExternalSchemas))
 	for _, schema := range g.GrantedSchemas {
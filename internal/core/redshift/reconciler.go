@@ -6,6 +6,12 @@ import (
 
 type ReconcilerConfig struct {
 	RevokeAccessToPublicSchema bool
+	//Workers is the number of tasks that are run at the same time, they are run one at a time if it is 0 or 1
+	Workers int
+	//MaxConnectionsPerCluster caps the tasks running against the same cluster, each of which uses a single connection. Zero disables the cap.
+	MaxConnectionsPerCluster int
+	//Retries decides how often a failed task is run again, tasks are not retried by default
+	Retries RetryPolicy
 }
 
 func DefaultReconcilerConfig() ReconcilerConfig {
@@ -280,6 +286,19 @@ func (d *Reconciler) lookupCreateDatabaseTask(clusterIdentifier string, name str
 	return nil
 }
 
+func (d *Reconciler) lookupRevokeAccessTask(database *Database, groupName string, schemaName string) *Task {
+	for _, task := range d.tasks {
+		if task.taskType == RevokeAccess &&
+			task.model.(*GrantsModel).Database.ClusterIdentifier == database.ClusterIdentifier &&
+			task.model.(*GrantsModel).Database.Name == database.Name &&
+			task.model.(*GrantsModel).GroupName == groupName &&
+			task.model.(*GrantsModel).SchemaName == schemaName {
+			return task
+		}
+	}
+	return nil
+}
+
 func (d *Reconciler) createUser(clusterIdentifier string, user *User) {
 
 	createUserTask := d.add(newCreateUserTask(clusterIdentifier, user))
@@ -353,7 +372,7 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 
 	for _, schema := range group.GrantedSchemas {
 
-		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name))
+		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name, schema.Privilege))
 
 		if createDatabaseTask != nil {
 			grantAccessTask.dependsOn(createDatabaseTask)
@@ -375,7 +394,8 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 
 	for _, schema := range group.GrantedExternalSchemas {
 
-		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name))
+		//external schemas are read only
+		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name, ReadPrivilege))
 
 		if createDatabaseTask != nil {
 			grantAccessTask.dependsOn(createDatabaseTask)
@@ -394,6 +414,21 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 			grantAccessTask.dependsOn(createGroupTask)
 		}
 	}
+
+	for _, table := range group.GrantedTables {
+
+		//tables are not managed, so there is nothing to create before granting access to it
+		grantTableAccessTask := d.add(newGrantTableAccessTask(database, table, group.Name))
+
+		if createDatabaseTask != nil {
+			grantTableAccessTask.dependsOn(createDatabaseTask)
+		}
+
+		createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, group.Name)
+		if createGroupTask != nil {
+			grantTableAccessTask.dependsOn(createGroupTask)
+		}
+	}
 }
 
 func (d *Reconciler) dropDatabaseGroup(database *Database, group *DatabaseGroup) {
@@ -419,6 +454,16 @@ func (d *Reconciler) dropDatabaseGroup(database *Database, group *DatabaseGroup)
 			dropGroupTask.dependsOn(revokeAccessTask)
 		}
 	}
+
+	for _, table := range group.GrantedTables {
+
+		revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, table, group.Name))
+
+		dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, group.Name)
+		if dropGroupTask != nil {
+			dropGroupTask.dependsOn(revokeTableAccessTask)
+		}
+	}
 }
 
 func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGroup, desired *DatabaseGroup) {
@@ -458,7 +503,7 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 		grantCurrent := current.LookupGrantedSchema(schema.Name)
 
 		if grantCurrent == nil {
-			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name))
+			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name, schema.Privilege))
 
 			createSchemaTask := d.add(newCreateSchemaTask(database, schema))
 			grantAccessTask.dependsOn(createSchemaTask)
@@ -467,6 +512,9 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 			if createGroupTask != nil {
 				grantAccessTask.dependsOn(createGroupTask)
 			}
+		} else if grantCurrent.Privilege.OrDefault() != schema.Privilege.OrDefault() {
+			//granting access replaces the privileges currently granted on the schema
+			d.add(newGrantAccessTask(database, schema.Name, desired.Name, schema.Privilege))
 		}
 	}
 
@@ -476,7 +524,7 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 
 		if grantCurrent == nil {
 
-			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name))
+			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name, ReadPrivilege))
 
 			createSchemaTask := d.add(newCreateExternalSchemaTask(database, schema))
 			grantAccessTask.dependsOn(createSchemaTask)
@@ -487,4 +535,46 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 			}
 		}
 	}
+
+	for _, table := range current.GrantedTables {
+
+		tableDesired := desired.LookupGrantedTable(table.Schema, table.Name)
+
+		if tableDesired == nil {
+			revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, table, current.Name))
+
+			dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, current.Name)
+			if dropGroupTask != nil {
+				dropGroupTask.dependsOn(revokeTableAccessTask)
+			}
+		}
+	}
+
+	for _, table := range desired.GrantedTables {
+
+		tableCurrent := current.LookupGrantedTable(table.Schema, table.Name)
+
+		if tableCurrent != nil && tableCurrent.SameColumns(table) {
+			continue
+		}
+
+		grantTableAccessTask := d.add(newGrantTableAccessTask(database, table, desired.Name))
+
+		if tableCurrent != nil {
+			//the columns that are no longer granted are revoked by revoking the current grant before granting the new one
+			revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, tableCurrent, current.Name))
+			grantTableAccessTask.dependsOn(revokeTableAccessTask)
+		}
+
+		//revoking access to the schema revokes access to all of its tables
+		revokeAccessTask := d.lookupRevokeAccessTask(database, current.Name, table.Schema)
+		if revokeAccessTask != nil {
+			grantTableAccessTask.dependsOn(revokeAccessTask)
+		}
+
+		createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, desired.Name)
+		if createGroupTask != nil {
+			grantTableAccessTask.dependsOn(createGroupTask)
+		}
+	}
 }
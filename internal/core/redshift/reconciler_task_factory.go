@@ -47,11 +47,12 @@ func newCreateDatabaseTask(clusterIdentifier string, model *Database) *Task {
 	})
 }
 
-func newGrantAccessTask(database *Database, schemaName string, groupName string) *Task {
-	return NewTask(fmt.Sprintf("%s->%s", groupName, schemaName), GrantAccess, &GrantsModel{
+func newGrantAccessTask(database *Database, schemaName string, groupName string, privilege Privilege) *Task {
+	return NewTask(fmt.Sprintf("%s->%s(%s)", groupName, schemaName, privilege.OrDefault()), GrantAccess, &GrantsModel{
 		GroupName:  groupName,
 		SchemaName: schemaName,
 		Database:   database,
+		Privilege:  privilege.OrDefault(),
 	})
 }
 
@@ -63,6 +64,22 @@ func newRevokeAccessTask(database *Database, schemaName string, groupName string
 	})
 }
 
+func newGrantTableAccessTask(database *Database, table *Table, groupName string) *Task {
+	return NewTask(fmt.Sprintf("%s->%s%v", groupName, table.Identifier(), table.Columns), GrantTableAccess, &TableGrantsModel{
+		GroupName: groupName,
+		Table:     table,
+		Database:  database,
+	})
+}
+
+func newRevokeTableAccessTask(database *Database, table *Table, groupName string) *Task {
+	return NewTask(fmt.Sprintf("%s->%s%v", groupName, table.Identifier(), table.Columns), RevokeTableAccess, &TableGrantsModel{
+		GroupName: groupName,
+		Table:     table,
+		Database:  database,
+	})
+}
+
 func newAddToGroupTask(clusterIdentifier string, model *User, group *Group) *Task {
 	return NewTask(fmt.Sprintf("%s->%s", model.Name, group.Name), AddToGroup, &MembershipModel{
 		ClusterIdentifier: clusterIdentifier,
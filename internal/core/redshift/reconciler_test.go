@@ -37,4 +37,88 @@ func Test_Dag1(t *testing.T) {
 	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
 
 	assert.Equal(8, dag.NumTasks())
+	assert.Contains(dag.Describe(), "CreateUser(jwr_bianalyst)")
+	assert.Contains(dag.Plan(), PlannedTask{Type: CreateUser, Identifier: "jwr_bianalyst", ClusterIdentifier: "dev"})
+	assert.Contains(dag.Plan(), PlannedTask{Type: GrantAccess, Identifier: "bianalyst->public(read)", ClusterIdentifier: "dev", DatabaseName: "jwr"})
+	assert.Empty(Reconcile(&desired, &desired, DefaultReconcilerConfig()).Describe(), "there are no tasks when nothing has drifted")
+}
+
+func Test_PrivilegeChange(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildDesired()
+	desired := buildDesired()
+	desired.LookupCluster("dev").LookupDatabase("jwr").LookupGroup("bianalyst").GrantSchema(&Schema{Name: "public", Privilege: WritePrivilege})
+
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	waiting := dag.GetWaiting()
+	assert.Len(waiting, 1, "only the privilege of the existing grant is changed")
+	assert.Equal(GrantAccess, waiting[0].taskType)
+	assert.Equal(WritePrivilege, waiting[0].model.(*GrantsModel).Privilege)
+
+	current = buildDesired()
+	desired = buildDesired()
+	desired.LookupCluster("dev").LookupDatabase("jwr").LookupGroup("bianalyst").GrantSchema(&Schema{Name: "public", Privilege: ReadPrivilege})
+
+	dag = Reconcile(&current, &desired, DefaultReconcilerConfig())
+	assert.Equal(0, dag.NumTasks(), "an empty privilege means read")
+
+	current = buildDesired()
+	current.LookupCluster("dev").LookupDatabase("jwr").LookupGroup("bianalyst").LookupGrantedSchema("public").Privilege = LegacyPrivilege
+	desired = buildDesired()
+
+	dag = Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	waiting = dag.GetWaiting()
+	assert.Len(waiting, 1, "a grant made before privilege levels were introduced is replaced by the declared level")
+	assert.Equal(ReadPrivilege, waiting[0].model.(*GrantsModel).Privilege)
+}
+
+func Test_TableGrants(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildDesired()
+	desired := buildDesired()
+	desired.LookupCluster("dev").LookupDatabase("jwr").LookupGroup("bianalyst").GrantTable(&Table{Schema: "pii", Name: "customers", Columns: []string{"name", "id"}})
+
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	waiting := dag.GetWaiting()
+	assert.Len(waiting, 1)
+	assert.Equal(GrantTableAccess, waiting[0].taskType)
+	assert.Equal([]string{"id", "name"}, waiting[0].model.(*TableGrantsModel).Table.Columns, "columns are sorted")
+
+	current = buildDesired()
+	current.LookupCluster("dev").LookupDatabase("jwr").LookupGroup("bianalyst").GrantTable(&Table{Schema: "pii", Name: "customers", Columns: []string{"id", "name", "ssn"}})
+
+	dag = Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(2, dag.NumTasks(), "the current columns are revoked before the desired columns are granted")
+	waiting = dag.GetWaiting()
+	assert.Len(waiting, 1)
+	assert.Equal(RevokeTableAccess, waiting[0].taskType)
+
+	desired = buildDesired()
+	dag = Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	waiting = dag.GetWaiting()
+	assert.Len(waiting, 1)
+	assert.Equal(RevokeTableAccess, waiting[0].taskType, "tables that are no longer granted are revoked")
+}
+
+func Test_TableGrantsAreMerged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	group := DatabaseGroup{}
+	group.GrantTable(&Table{Schema: "pii", Name: "customers", Columns: []string{"name"}})
+	group.GrantTable(&Table{Schema: "PII", Name: "customers", Columns: []string{"id", "name"}})
+	assert.Len(group.GrantedTables, 1)
+	assert.Equal([]string{"id", "name"}, group.GrantedTables[0].Columns)
+
+	group.GrantTable(&Table{Schema: "pii", Name: "customers"})
+	assert.Empty(group.GrantedTables[0].Columns, "granting the whole table includes all columns")
 }
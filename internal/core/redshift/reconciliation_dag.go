@@ -4,6 +4,23 @@ import (
 	"fmt"
 )
 
+// PlannedTask describes a task of the DAG without running it.
+type PlannedTask struct {
+	Type              TaskType
+	Identifier        string
+	ClusterIdentifier string
+	DatabaseName      string //empty for tasks on users and groups
+}
+
+// DagResult summarizes the outcome of running a DAG.
+type DagResult struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Retried   int //the tasks that were run more than once
+	Attempts  int //the number of times the tasks were run, including retries
+}
+
 type ReconciliationDag struct {
 	tasks []*Task
 }
@@ -31,7 +48,7 @@ func (d *ReconciliationDag) GetFailed() []*Task {
 	var result []*Task
 
 	for _, task := range d.tasks {
-		if task.state == Failed {
+		if task.State() == Failed {
 			result = append(result, task)
 		}
 	}
@@ -40,13 +57,55 @@ func (d *ReconciliationDag) GetFailed() []*Task {
 
 func (d *ReconciliationDag) PendingExists() bool {
 	for _, task := range d.tasks {
-		if task.state == Pending {
+		if task.State() == Pending {
 			return true
 		}
 	}
 	return false
 }
 
+func (d *ReconciliationDag) Result() DagResult {
+	result := DagResult{}
+
+	for _, task := range d.tasks {
+		switch task.State() {
+		case Success:
+			result.Succeeded++
+		case Failed:
+			result.Failed++
+		case Skipped:
+			result.Skipped++
+		}
+		attempts := task.Attempts()
+		if attempts > 1 {
+			result.Retried++
+		}
+		result.Attempts += attempts
+	}
+	return result
+}
+
+//Returns a short description of every task, e.g. CreateUser(jwr_bianalyst).
+func (d *ReconciliationDag) Describe() []string {
+	var result []string
+
+	for _, t := range d.tasks {
+		result = append(result, fmt.Sprintf("%s(%s)", t.taskType.String(), t.identifier))
+	}
+	return result
+}
+
+//Returns the tasks in the order they were added to the DAG.
+func (d *ReconciliationDag) Plan() []PlannedTask {
+	var result []PlannedTask
+
+	for _, t := range d.tasks {
+		clusterIdentifier, databaseName := t.Location()
+		result = append(result, PlannedTask{Type: t.taskType, Identifier: t.identifier, ClusterIdentifier: clusterIdentifier, DatabaseName: databaseName})
+	}
+	return result
+}
+
 func (d *ReconciliationDag) String() string {
 	var result string
 
@@ -0,0 +1,44 @@
+package redshift
+
+import (
+	"time"
+)
+
+// RetryPolicy decides how often a failed task is run again.
+type RetryPolicy struct {
+	MaxAttempts    int                  //the number of times a task is run before it fails, a task is only run once if it is 0 or 1
+	InitialBackoff time.Duration        //the wait before the first retry, doubled before every following retry
+	MaxBackoff     time.Duration        //caps the wait between retries, it is not capped if zero
+	Retryable      func(err error) bool //returns true for transient errors, nothing is retried if it is nil
+}
+
+//Returns the wait before the given retry, the first retry being 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	result := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		result *= 2
+		if p.MaxBackoff > 0 && result >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && result > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return result
+}
+
+func (p RetryPolicy) shouldRetry(attempts int, err error) bool {
+	return attempts < p.MaxAttempts && p.Retryable != nil && p.Retryable(err)
+}
+
+//Runs the task until it succeeds, fails with an error that is not retryable or runs out of attempts. The error of the last attempt is returned.
+func executeWithRetries(taskRunner TaskRunner, task *Task, policy RetryPolicy) error {
+	for {
+		attempts := task.attempt()
+		err := ExecuteTask(taskRunner, task)
+		if err == nil || !policy.shouldRetry(attempts, err) {
+			return err
+		}
+		time.Sleep(policy.backoff(attempts))
+	}
+}
@@ -3,6 +3,8 @@ package redshift
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 )
 
 type TaskType int
@@ -19,6 +21,8 @@ const (
 	RevokeAccess
 	AddToGroup
 	RemoveFromGroup
+	GrantTableAccess
+	RevokeTableAccess
 )
 
 type TaskState int
@@ -31,9 +35,24 @@ const (
 	Skipped
 )
 
+func (s TaskState) String() string {
+	return [...]string{"Running", "Pending", "Success", "Failed", "Skipped"}[s]
+}
+
 func (t TaskType) String() string {
 	return [...]string{"CreateUser", "DropUser", "CreateGroup", "DropGroup", "CreateSchema",
-		"CreateExternalSchema", "CreateDatabase", "GrantAccess", "RevokeAccess", "AddToGroup", "RemoveFromGroup"}[t]
+		"CreateExternalSchema", "CreateDatabase", "GrantAccess", "RevokeAccess", "AddToGroup", "RemoveFromGroup",
+		"GrantTableAccess", "RevokeTableAccess"}[t]
+}
+
+//Returns true for the tasks that remove users, groups or access.
+func (t TaskType) Destructive() bool {
+	switch t {
+	case DropUser, DropGroup, RevokeAccess, RevokeTableAccess, RemoveFromGroup:
+		return true
+	default:
+		return false
+	}
 }
 
 type Equatable interface {
@@ -47,40 +66,141 @@ type Task struct {
 	upStream   []*Task
 	downStream []*Task
 	state      TaskState
+	attempts   int           //the number of times the task has been run
+	started    time.Time     //zero until the task is started
+	duration   time.Duration //the time from the task was started until it succeeded or failed, including retries
+	stateLock  sync.Mutex    //tasks may be run while the state of other tasks is read
 }
 
 func NewTask(identifier string, taskType TaskType, model Equatable) *Task {
 	return &Task{identifier: identifier, taskType: taskType, model: model, state: Pending}
 }
 
+func (t *Task) Type() TaskType {
+	return t.taskType
+}
+
+func (t *Task) Identifier() string {
+	return t.identifier
+}
+
+func (t *Task) State() TaskState {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	return t.state
+}
+
+func (t *Task) Attempts() int {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	return t.attempts
+}
+
+func (t *Task) Duration() time.Duration {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	return t.duration
+}
+
+//Counts another attempt at running the task and returns the number of attempts so far.
+func (t *Task) attempt() int {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	t.attempts++
+	return t.attempts
+}
+
+func (t *Task) setState(state TaskState) {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	t.state = state
+}
+
+//Returns the cluster and database the task is run against. The database is empty for users and groups, which belong to the whole cluster.
+func (t *Task) Location() (string, string) {
+	switch model := t.model.(type) {
+	case *UserModel:
+		return model.ClusterIdentifier, ""
+	case *GroupModel:
+		return model.ClusterIdentifier, ""
+	case *MembershipModel:
+		return model.ClusterIdentifier, ""
+	case *DatabaseModel:
+		return model.ClusterIdentifier, model.Database.Name
+	case *SchemaModel:
+		return model.Database.ClusterIdentifier, model.Database.Name
+	case *ExternalSchemaModel:
+		return model.Database.ClusterIdentifier, model.Database.Name
+	case *GrantsModel:
+		return model.Database.ClusterIdentifier, model.Database.Name
+	case *TableGrantsModel:
+		return model.Database.ClusterIdentifier, model.Database.Name
+	default:
+		return "", ""
+	}
+}
+
+//Describes what the task changes beyond its identifier, e.g. the privilege granted, or returns an empty string if there is nothing to add.
+func (t *Task) Detail() string {
+	switch model := t.model.(type) {
+	case *MembershipModel:
+		return fmt.Sprintf("group %s", model.GroupName)
+	case *ExternalSchemaModel:
+		return fmt.Sprintf("glue database %s", model.Schema.GlueDatabaseName)
+	case *GrantsModel:
+		if t.taskType == GrantAccess {
+			return fmt.Sprintf("%s on schema %s", model.Privilege.OrDefault(), model.SchemaName)
+		}
+		return fmt.Sprintf("schema %s", model.SchemaName)
+	case *TableGrantsModel:
+		return fmt.Sprintf("table %s", model.Table.Identifier())
+	default:
+		return ""
+	}
+}
+
 func (t *Task) Skip() {
-	t.state = Skipped
+	t.setState(Skipped)
 }
 
 func (t *Task) Success() {
-	t.state = Success
+	t.finish(Success)
 }
 
 func (t *Task) Failed() {
-	t.state = Failed
+	t.finish(Failed)
 }
 
 func (t *Task) Start() {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
 	t.state = Running
+	t.started = time.Now()
+}
+
+func (t *Task) finish(state TaskState) {
+	t.stateLock.Lock()
+	defer t.stateLock.Unlock()
+	t.state = state
+	if !t.started.IsZero() {
+		t.duration = time.Since(t.started)
+	}
 }
 
 func (t *Task) isDone() bool {
-	return t.state == Success || t.state == Failed || t.state == Skipped
+	state := t.State()
+	return state == Success || state == Failed || state == Skipped
 }
 
 func (t *Task) IsWaiting() bool {
-	return t.allUpstreamDone() && t.state == Pending
+	return t.allUpstreamDone() && t.State() == Pending
 }
 
 func (t *Task) CannotRun() bool {
 
 	for _, parent := range t.upStream {
-		if parent.state == Failed || parent.state == Skipped {
+		state := parent.State()
+		if state == Failed || state == Skipped {
 			return true
 		}
 	}
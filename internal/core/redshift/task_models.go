@@ -4,6 +4,7 @@ type GrantsModel struct {
 	Database   *Database
 	SchemaName string
 	GroupName  string
+	Privilege  Privilege //the level of access to grant, not used when access is revoked
 }
 
 func (s *GrantsModel) Equals(rhs Equatable) bool {
@@ -16,7 +17,28 @@ func (s *GrantsModel) Equals(rhs Equatable) bool {
 	}
 	return s.Database.Name == other.Database.Name &&
 		s.GroupName == other.GroupName &&
-		s.SchemaName == other.SchemaName
+		s.SchemaName == other.SchemaName &&
+		s.Privilege.OrDefault() == other.Privilege.OrDefault()
+}
+
+type TableGrantsModel struct {
+	Database  *Database
+	GroupName string
+	Table     *Table //the table and columns to grant or revoke SELECT on
+}
+
+func (s *TableGrantsModel) Equals(rhs Equatable) bool {
+	if rhs == nil {
+		return false
+	}
+	other, ok := rhs.(*TableGrantsModel)
+	if !ok {
+		return false
+	}
+	return s.Database.Name == other.Database.Name &&
+		s.GroupName == other.GroupName &&
+		s.Table.Identifier() == other.Table.Identifier() &&
+		s.Table.SameColumns(other.Table)
 }
 
 type MembershipModel struct {
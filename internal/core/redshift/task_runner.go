@@ -17,6 +17,8 @@ type TaskRunner interface {
 	RevokeAccess(model *GrantsModel) error
 	AddToGroup(model *MembershipModel) error
 	RemoveFromGroup(model *MembershipModel) error
+	GrantTableAccess(model *TableGrantsModel) error
+	RevokeTableAccess(model *TableGrantsModel) error
 }
 
 func ExecuteTask(taskRunner TaskRunner, task *Task) error {
@@ -43,6 +45,10 @@ func ExecuteTask(taskRunner TaskRunner, task *Task) error {
 		return taskRunner.AddToGroup(task.model.(*MembershipModel))
 	case RemoveFromGroup:
 		return taskRunner.RemoveFromGroup(task.model.(*MembershipModel))
+	case GrantTableAccess:
+		return taskRunner.GrantTableAccess(task.model.(*TableGrantsModel))
+	case RevokeTableAccess:
+		return taskRunner.RevokeTableAccess(task.model.(*TableGrantsModel))
 	default:
 		return fmt.Errorf("unexpected task type: %s", task.taskType.String())
 	}
@@ -85,7 +91,7 @@ func (t *TaskPrinter) CreateDatabase(model *DatabaseModel) error {
 	return nil
 }
 func (t *TaskPrinter) GrantAccess(model *GrantsModel) error {
-	t.logger.Info("GrantAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "schemaName", model.SchemaName)
+	t.logger.Info("GrantAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "schemaName", model.SchemaName, "privilege", model.Privilege)
 	return nil
 }
 func (t *TaskPrinter) RevokeAccess(model *GrantsModel) error {
@@ -100,3 +106,11 @@ func (t *TaskPrinter) RemoveFromGroup(model *MembershipModel) error {
 	t.logger.Info("RemoveFromGroup", "clusterIdentifier", model.ClusterIdentifier, "username", model.Username, "groupName", model.GroupName)
 	return nil
 }
+func (t *TaskPrinter) GrantTableAccess(model *TableGrantsModel) error {
+	t.logger.Info("GrantTableAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "table", model.Table.Identifier(), "columns", model.Table.Columns)
+	return nil
+}
+func (t *TaskPrinter) RevokeTableAccess(model *TableGrantsModel) error {
+	t.logger.Info("RevokeTableAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "table", model.Table.Identifier(), "columns", model.Table.Columns)
+	return nil
+}
@@ -6,38 +6,188 @@ import (
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/iam"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"strings"
+	"time"
 )
 
 type Resolver struct {
+	IdentityProvider iam.IdentityProvider //the default identity provider is used if it is not set
+	Datalake         iam.DatalakeAccess
+}
+
+func (r *Resolver) identityProvider() iam.IdentityProvider {
+	result := iam.DefaultIdentityProvider()
+	if r.IdentityProvider.Name != "" {
+		result.Name = r.IdentityProvider.Name
+	}
+	if r.IdentityProvider.DefaultSessionDuration != 0 {
+		result.DefaultSessionDuration = r.IdentityProvider.DefaultSessionDuration
+	}
+	return result
+}
+
+func (r *Resolver) sessionDuration(role *hubble.Role) time.Duration {
+	if role.SessionDuration != 0 {
+		return role.SessionDuration
+	}
+	return r.identityProvider().DefaultSessionDuration
+}
+
+func (r *Resolver) datalakeArn(service string, resource string) string {
+	region, accountId := r.Datalake.Region, r.Datalake.AccountId
+	if region == "" {
+		region = "*"
+	}
+	if accountId == "" {
+		accountId = "*"
+	}
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, region, accountId, resource)
+}
+
+//Returns the S3 bucket and the prefix under which the data of the glue database is stored, or empty strings if its location is unknown.
+func (r *Resolver) datalakeLocation(glueDb *hubble.GlueDatabase) (string, string) {
+	location := glueDb.Location
+	if location == "" && r.Datalake.LocationTemplate != "" {
+		location = fmt.Sprintf(r.Datalake.LocationTemplate, glueDb.Name)
+	}
+	location = strings.Trim(strings.TrimPrefix(location, "s3://"), "/")
+	if location == "" {
+		return "", ""
+	}
+	parts := strings.SplitN(location, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+//The statements that allow a role to query its glue databases from Athena: reading the glue catalog, running queries in the workgroup and reading the data in S3.
+func (r *Resolver) datalakeStatements(role *hubble.Role) []*iam.PolicyStatement {
+	if len(role.GrantedGlueDatabases) == 0 {
+		return nil
+	}
+
+	workgroup := r.Datalake.AthenaWorkgroup
+	if workgroup == "" {
+		workgroup = "primary"
+	}
+
+	glueResources := []string{r.datalakeArn("glue", "catalog")}
+	var buckets, objects []string
+	for _, glueDb := range role.GrantedGlueDatabases {
+		glueResources = append(glueResources,
+			r.datalakeArn("glue", fmt.Sprintf("database/%s", glueDb.Name)),
+			r.datalakeArn("glue", fmt.Sprintf("table/%s/*", glueDb.Name)))
+
+		bucket, prefix := r.datalakeLocation(glueDb)
+		if bucket == "" {
+			continue
+		}
+		bucketArn := fmt.Sprintf("arn:aws:s3:::%s", bucket)
+		if !containsString(buckets, bucketArn) {
+			buckets = append(buckets, bucketArn)
+		}
+		if prefix == "" {
+			objects = append(objects, fmt.Sprintf("%s/*", bucketArn))
+		} else {
+			objects = append(objects, fmt.Sprintf("%s/%s/*", bucketArn, prefix))
+		}
+	}
+
+	result := []*iam.PolicyStatement{
+		{
+			Sid:       "DatalakeGlueRead",
+			Effect:    "Allow",
+			Actions:   []string{"glue:GetDatabase", "glue:GetDatabases", "glue:GetTable", "glue:GetTables", "glue:GetPartition", "glue:GetPartitions"},
+			Resources: glueResources,
+		},
+		{
+			Sid:       "DatalakeAthenaQuery",
+			Effect:    "Allow",
+			Actions:   []string{"athena:GetWorkGroup", "athena:StartQueryExecution", "athena:StopQueryExecution", "athena:GetQueryExecution", "athena:GetQueryResults"},
+			Resources: []string{r.datalakeArn("athena", fmt.Sprintf("workgroup/%s", workgroup))},
+		},
+	}
+	if len(buckets) > 0 {
+		result = append(result,
+			&iam.PolicyStatement{
+				Sid:       "DatalakeS3List",
+				Effect:    "Allow",
+				Actions:   []string{"s3:GetBucketLocation", "s3:ListBucket"},
+				Resources: buckets,
+			},
+			&iam.PolicyStatement{
+				Sid:       "DatalakeS3Read",
+				Effect:    "Allow",
+				Actions:   []string{"s3:GetObject"},
+				Resources: objects,
+			})
+	}
+	return result
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
 }
 
 //transforms the given hubble model into separate models for the 3 systems we want to reconcile
 func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, google.Model) {
 
+	identityProvider := r.identityProvider()
+
 	redshiftModel := redshift.Model{}
 	iamModel := iam.Model{}
-	googleModel := google.Model{}
+	googleModel := google.Model{IdentityProvider: identityProvider.Name}
 
 	for _, db := range model.Databases {
 		cluster := redshiftModel.DeclareCluster(db.ClusterIdentifier)
 		cluster.DeclareDatabase(db.Name)
 	}
 
+	//clusters hosting dev databases are managed even if no role grants access to them, so users can be removed from them
+	for _, db := range model.DevDatabases {
+		redshiftModel.DeclareCluster(db.ClusterIdentifier)
+	}
+
 	for _, role := range model.Roles {
-		iamModel.DeclareRole(role.Name)
+		iamRole := iamModel.DeclareRole(role.Name)
+		iamRole.IdentityProvider = identityProvider.Name
+		iamRole.SessionDuration = r.sessionDuration(role)
+		for _, statement := range role.PolicyStatements {
+			iamRole.Statements = append(iamRole.Statements, &iam.PolicyStatement{
+				Sid:       statement.Sid,
+				Effect:    statement.Effect,
+				Actions:   statement.Actions,
+				Resources: statement.Resources,
+			})
+		}
+		iamRole.Statements = append(iamRole.Statements, r.datalakeStatements(role)...)
 	}
 
 	for _, user := range model.Users {
 
 		googleLogin := googleModel.DeclareUser(user.Email)
+		googleLogin.SessionDuration = identityProvider.DefaultSessionDuration
 
-		for _, role := range user.AssignedTo {
+		for i, role := range user.AssignedTo {
 
 			//Allow the user to log in with the role
 			googleLogin.Assign(role.Name)
 
+			//The session duration of the user can not exceed the session duration of any of its roles
+			if i == 0 || r.sessionDuration(role) < googleLogin.SessionDuration {
+				googleLogin.SessionDuration = r.sessionDuration(role)
+			}
+
 			//Declare an AWS role for the given role
 			iamRole := iamModel.DeclareRole(role.Name)
+			iamRole.IdentityProvider = identityProvider.Name
+			iamRole.SessionDuration = r.sessionDuration(role)
 
 			userAndRoleUsername := fmt.Sprintf("%s_%s", user.Username, role.Name)
 
@@ -56,7 +206,10 @@ func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, googl
 				databaseGroup := database.DeclareGroup(role.Name)
 				databaseGroup.GrantSchema(&redshift.Schema{Name: "public"})
 				for _, schema := range role.Acl {
-					databaseGroup.GrantSchema(&redshift.Schema{Name: string(schema)}) //TODO: is it ok to assume that there is a schema with name = dataset?
+					databaseGroup.GrantSchema(&redshift.Schema{Name: string(schema), Privilege: redshift.Privilege(role.Privileges[schema])}) //TODO: is it ok to assume that there is a schema with name = dataset?
+				}
+				for _, grant := range role.TableGrants {
+					databaseGroup.GrantTable(&redshift.Table{Schema: string(grant.DataSet), Name: grant.Table, Columns: grant.Columns})
 				}
 
 				//Declare a redshift user for the user/role and add it to the group
@@ -3,8 +3,11 @@ package resolver
 import (
 	"fmt"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 type TestData struct {
@@ -42,6 +45,7 @@ func generateTestData() TestData {
 		GrantedDevDatabases:  []*hubble.DevDatabase{},
 		GrantedGlueDatabases: []*hubble.GlueDatabase{},
 		Acl:                  []hubble.DataSet{"bi", "core"},
+		TableGrants:          []hubble.TableGrant{{DataSet: "pii", Table: "customers", Columns: []string{"id"}}},
 	}
 
 	dbtDeveloperRole := hubble.Role{
@@ -50,6 +54,7 @@ func generateTestData() TestData {
 		GrantedDevDatabases:  []*hubble.DevDatabase{&dev},
 		GrantedGlueDatabases: []*hubble.GlueDatabase{},
 		Acl:                  []hubble.DataSet{"bi", "core"},
+		Privileges:           map[hubble.DataSet]hubble.Privilege{"bi": hubble.WritePrivilege},
 		Policies:             []*hubble.PolicyReference{&allowAccessToTmpBucketPolicy},
 	}
 
@@ -153,6 +158,8 @@ func Test_BiAnalyst(t *testing.T) {
 	group := database.LookupGroup(data.biAnalystRole.Name)
 	assert.NotNil(group, "a user group with the name of the role has been registered")
 	assert.Contains(group.Granted(), "bi", "group has been granted access to the expected schemas")
+	assert.NotContains(group.Granted(), "pii", "tables are granted without access to the schema")
+	assert.NotNil(group.LookupGrantedTable("pii", "customers"), "group has been granted access to the table")
 
 	dbUser := database.LookupUser(dbUsername)
 	assert.NotNil(dbUser, "a user name of the role and user has been registered")
@@ -171,3 +178,144 @@ func Test_BiAnalyst(t *testing.T) {
 	access := policy.LookupDatabase(data.unstable.ClusterIdentifier, data.unstable.Name)
 	assert.NotNil(access, "access has been granted for the user to the unstable/prod database")
 }
+
+func Test_UnassignedUsers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+
+	unassigned := hubble.User{Username: data.biAnalyst.Username, Email: data.biAnalyst.Email}
+
+	model := hubble.Model{
+		Databases:    []*hubble.Database{&data.unstable},
+		DevDatabases: []*hubble.DevDatabase{&data.dev},
+		Users:        []*hubble.User{&unassigned},
+	}
+
+	resolver := Resolver{}
+	redshiftModel, iamModel, googleModel := resolver.Resolve(model)
+
+	assert.NotNil(redshiftModel.LookupCluster(data.unstable.ClusterIdentifier), "cluster of the database is managed")
+	assert.NotNil(redshiftModel.LookupCluster(data.dev.ClusterIdentifier), "cluster of the dev database is managed")
+	assert.Empty(redshiftModel.LookupCluster(data.unstable.ClusterIdentifier).Users, "no redshift users are declared")
+	assert.Empty(iamModel.Roles, "no AWS roles are declared")
+
+	user := googleModel.LookupUser(unassigned.Email)
+	assert.NotNil(user, "google login is registered so its roles can be cleared")
+	assert.Empty(user.AssignedTo(), "google login has no roles")
+}
+
+func Test_Privileges(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+
+	model := hubble.Model{
+		Databases: []*hubble.Database{&data.unstable},
+		Users:     []*hubble.User{&data.biAnalyst, &data.dbtDeveloper},
+		Roles:     []*hubble.Role{&data.biAnalystRole, &data.dbtDeveloperRole},
+	}
+
+	resolver := Resolver{}
+	redshiftModel, _, _ := resolver.Resolve(model)
+
+	database := redshiftModel.LookupCluster(data.unstable.ClusterIdentifier).LookupDatabase(data.unstable.Name)
+
+	developers := database.LookupGroup(data.dbtDeveloperRole.Name)
+	assert.Equal(redshift.WritePrivilege, developers.LookupGrantedSchema("bi").Privilege)
+	assert.Equal(redshift.ReadPrivilege, developers.LookupGrantedSchema("core").Privilege.OrDefault(), "data sets without a privilege can be read")
+	assert.Equal(redshift.ReadPrivilege, developers.LookupGrantedSchema("public").Privilege.OrDefault())
+
+	analysts := database.LookupGroup(data.biAnalystRole.Name)
+	assert.Equal(redshift.ReadPrivilege, analysts.LookupGrantedSchema("bi").Privilege.OrDefault())
+}
+
+func Test_SessionDuration(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+	data.dbtDeveloperRole.SessionDuration = time.Hour
+	data.biAnalyst.AssignedTo = []*hubble.Role{&data.biAnalystRole, &data.dbtDeveloperRole}
+	data.dbtDeveloper.AssignedTo = []*hubble.Role{&data.dbtDeveloperRole}
+
+	model := hubble.Model{
+		Databases: []*hubble.Database{&data.unstable},
+		Users:     []*hubble.User{&data.biAnalyst, &data.dbtDeveloper},
+		Roles:     []*hubble.Role{&data.biAnalystRole, &data.dbtDeveloperRole},
+	}
+
+	resolver := Resolver{IdentityProvider: iam.IdentityProvider{Name: "Okta", DefaultSessionDuration: 8 * time.Hour}}
+	_, iamModel, googleModel := resolver.Resolve(model)
+
+	assert.Equal("Okta", googleModel.IdentityProvider)
+	assert.Equal("Okta", iamModel.LookupRole(data.biAnalystRole.Name).IdentityProvider)
+	assert.Equal(8*time.Hour, iamModel.LookupRole(data.biAnalystRole.Name).SessionDuration, "the default session duration is used")
+	assert.Equal(time.Hour, iamModel.LookupRole(data.dbtDeveloperRole.Name).SessionDuration)
+
+	assert.Equal(time.Hour, googleModel.LookupUser(data.biAnalyst.Email).SessionDuration, "the shortest session duration of the roles of the user is used")
+
+	_, iamModel, _ = (&Resolver{}).Resolve(model)
+	assert.Equal("GoogleApps", iamModel.LookupRole(data.biAnalystRole.Name).IdentityProvider, "the default identity provider is used if none is configured")
+	assert.Equal(4*time.Hour, iamModel.LookupRole(data.biAnalystRole.Name).SessionDuration)
+}
+
+func Test_PolicyStatements(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+	data.biAnalystRole.PolicyStatements = []hubble.PolicyStatement{
+		{Effect: "Allow", Actions: []string{"s3:GetObject"}, Resources: []string{"arn:aws:s3:::lunar-reports/*"}},
+	}
+
+	model := hubble.Model{
+		Databases: []*hubble.Database{&data.unstable},
+		Roles:     []*hubble.Role{&data.biAnalystRole},
+	}
+
+	_, iamModel, _ := (&Resolver{}).Resolve(model)
+
+	statements := iamModel.LookupRole(data.biAnalystRole.Name).Statements
+	assert.Len(statements, 1, "roles without users get their statements too")
+	assert.Equal([]string{"s3:GetObject"}, statements[0].Actions)
+}
+
+func Test_DatalakeStatements(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+	rawEvents := hubble.GlueDatabase{ShortName: "rawevents", Name: "raw-events", Location: "s3://lunar-raw/events/"}
+	data.biAnalystRole.GrantedGlueDatabases = []*hubble.GlueDatabase{&data.lwgoeventsDatabase, &rawEvents}
+
+	model := hubble.Model{
+		Databases: []*hubble.Database{&data.unstable},
+		Roles:     []*hubble.Role{&data.biAnalystRole, &data.dbtDeveloperRole},
+	}
+
+	resolver := Resolver{Datalake: iam.DatalakeAccess{AccountId: "478824949770", Region: "eu-west-1", LocationTemplate: "s3://lunar-datalake/%s"}}
+	_, iamModel, _ := resolver.Resolve(model)
+
+	statements := iamModel.LookupRole(data.biAnalystRole.Name).Statements
+	assert.Len(statements, 4)
+	assert.Equal([]string{
+		"arn:aws:glue:eu-west-1:478824949770:catalog",
+		"arn:aws:glue:eu-west-1:478824949770:database/lw-go-events",
+		"arn:aws:glue:eu-west-1:478824949770:table/lw-go-events/*",
+		"arn:aws:glue:eu-west-1:478824949770:database/raw-events",
+		"arn:aws:glue:eu-west-1:478824949770:table/raw-events/*",
+	}, statements[0].Resources)
+	assert.Equal([]string{"arn:aws:athena:eu-west-1:478824949770:workgroup/primary"}, statements[1].Resources)
+	assert.Equal([]string{"arn:aws:s3:::lunar-datalake", "arn:aws:s3:::lunar-raw"}, statements[2].Resources)
+	assert.Equal([]string{"arn:aws:s3:::lunar-datalake/lw-go-events/*", "arn:aws:s3:::lunar-raw/events/*"}, statements[3].Resources, "the location of the glue database is used before the configured default")
+
+	assert.Empty(iamModel.LookupRole(data.dbtDeveloperRole.Name).Statements, "roles without glue databases are not given access to the data lake")
+
+	_, iamModel, _ = (&Resolver{}).Resolve(model)
+	statements = iamModel.LookupRole(data.biAnalystRole.Name).Statements
+	assert.Len(statements, 4)
+	assert.Equal([]string{"arn:aws:s3:::lunar-raw/events/*"}, statements[3].Resources, "glue databases without a known location are not readable in S3")
+}
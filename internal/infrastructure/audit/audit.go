@@ -0,0 +1,204 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/metrics"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
+
+//The results of a change.
+const (
+	Succeeded = "Succeeded"
+	Failed    = "Failed"
+)
+
+// Context identifies the HubbleRbac whose changes are being applied.
+type Context struct {
+	Resource     string //the namespace and name of the HubbleRbac
+	Generation   int64
+	Cause        string //the hubble.lunar.tech/change-cause annotation, e.g. the commit that changed the HubbleRbac
+	ApprovedPlan string //the hubble.lunar.tech/approved-plan annotation, if the changes were approved
+}
+
+// Entry is a change made, or attempted, in one of the managed systems.
+type Entry struct {
+	System   string //redshift, iam or google
+	Action   string //e.g. GrantAccess, PolicyDeleted or RolesUpdated
+	Target   string //the user, group, role or policy that was changed
+	Location string //the cluster and database of a redshift change or the role of an IAM change
+	Detail   string //e.g. the privilege granted or the roles assigned
+	Err      error  //the change failed if it is set
+}
+
+// Record is an entry of the audit log. Every record contains the hash of the record before it, so a record that is changed or removed breaks the chain.
+type Record struct {
+	Sequence     uint64    `json:"sequence"`
+	Time         time.Time `json:"time"`
+	System       string    `json:"system"`
+	Action       string    `json:"action"`
+	Target       string    `json:"target"`
+	Location     string    `json:"location,omitempty"`
+	Detail       string    `json:"detail,omitempty"`
+	Result       string    `json:"result"`
+	Error        string    `json:"error,omitempty"`
+	Resource     string    `json:"resource,omitempty"`
+	Generation   int64     `json:"generation,omitempty"`
+	Cause        string    `json:"cause,omitempty"`
+	ApprovedPlan string    `json:"approvedPlan,omitempty"`
+	PreviousHash string    `json:"previousHash"` //empty for the first record of a chain
+	Hash         string    `json:"hash"`
+}
+
+//Returns the hex encoded sha256 of the record with the hash left out.
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//ErrUnwritten is returned by Flush while records of the audit log are waiting to be written.
+var ErrUnwritten = fmt.Errorf("audit records could not be written")
+
+// Sink stores the records of the audit log.
+type Sink interface {
+	Write(record Record) error
+}
+
+// ResumableSink is a sink that returns the last record it has stored, so the chain is continued when the controller is restarted.
+// Only the FileSink is resumable, the other sinks start a new chain at sequence 1 on every restart.
+type ResumableSink interface {
+	Sink
+	Last() (*Record, error)
+}
+
+// Log chains the changes into records and writes them to the sink.
+// A nil Log drops the changes, so the audit log is optional for the appliers.
+type Log struct {
+	sink   Sink
+	logger logr.Logger
+
+	lock     sync.Mutex
+	context  Context
+	sequence uint64
+	lastHash string
+	pending  []Record //records that are chained but not yet written, oldest first
+}
+
+func NewLog(sink Sink, logger logr.Logger) (*Log, error) {
+	result := &Log{sink: sink, logger: logger}
+
+	if resumable, ok := sink.(ResumableSink); ok {
+		last, err := resumable.Last()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the last audit record: %w", err)
+		}
+		if last != nil {
+			result.sequence = last.Sequence
+			result.lastHash = last.Hash
+		}
+	}
+	return result, nil
+}
+
+//Attributes the changes to the given HubbleRbac until it is called again.
+func (l *Log) SetContext(context Context) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.context = context
+}
+
+//Appends the change to the audit log. A record that can't be written is kept and written before the next one,
+//so the chain isn't broken. Flush tells whether any records are still waiting to be written.
+func (l *Log) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	record := Record{
+		Sequence:     l.sequence + 1,
+		Time:         time.Now().UTC(),
+		System:       entry.System,
+		Action:       entry.Action,
+		Target:       entry.Target,
+		Location:     entry.Location,
+		Detail:       entry.Detail,
+		Result:       Succeeded,
+		Resource:     l.context.Resource,
+		Generation:   l.context.Generation,
+		Cause:        l.context.Cause,
+		ApprovedPlan: l.context.ApprovedPlan,
+		PreviousHash: l.lastHash,
+	}
+	if entry.Err != nil {
+		record.Result = Failed
+		record.Error = entry.Err.Error()
+	}
+
+	hash, err := record.computeHash()
+	if err != nil {
+		metrics.AuditWriteFailures.Inc()
+		l.logger.Error(err, "unable to hash audit record", "system", record.System, "action", record.Action, "target", record.Target)
+		return
+	}
+	record.Hash = hash
+
+	l.pending = append(l.pending, record)
+	l.sequence = record.Sequence
+	l.lastHash = record.Hash
+
+	_ = l.flush() //the failure is logged, and reported by the next call to Flush
+}
+
+//Writes the records that couldn't be written before. The records that still can't be written are kept for the next attempt,
+//and ErrUnwritten is returned. They are lost if the controller is restarted before they have been written.
+func (l *Log) Flush() error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.flush()
+}
+
+func (l *Log) flush() error {
+	for len(l.pending) > 0 {
+		record := l.pending[0]
+		err := l.sink.Write(record)
+		if err != nil {
+			metrics.AuditWriteFailures.Inc()
+			l.logger.Error(err, "unable to write audit record", "sequence", record.Sequence, "system", record.System, "action", record.Action, "target", record.Target, "pending", len(l.pending))
+			return fmt.Errorf("%w, %d are waiting to be written: %v", ErrUnwritten, len(l.pending), err)
+		}
+		l.pending = l.pending[1:]
+	}
+	return nil
+}
+
+//Appends the operations of the report that were run to the audit log, leaving out the ones that were skipped.
+func (l *Log) Reported(applyReport *report.ApplyReport) {
+	if l == nil || applyReport == nil {
+		return
+	}
+	for _, operation := range applyReport.Operations {
+		if operation.Outcome == report.Skipped {
+			continue
+		}
+		l.Record(Entry{System: operation.System, Action: operation.Type, Target: operation.Target, Location: operation.Location(), Detail: operation.Detail, Err: operation.Err})
+	}
+}
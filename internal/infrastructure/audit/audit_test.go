@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logrtesting "github.com/go-logr/logr/testing"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeRecords(log *Log) {
+	log.SetContext(Context{Resource: "datascience/hubble", Generation: 7, Cause: "commit 3f2a1c"})
+	log.Record(Entry{System: "redshift", Action: "GrantAccess", Target: "bianalyst", Location: "dev/jwr", Detail: "usage"})
+	log.Record(Entry{System: "iam", Action: "PolicyDeleted", Target: "jwr_bianalyst", Location: "BiAnalyst", Err: fmt.Errorf("access denied")})
+	log.Record(Entry{System: "google", Action: "RolesUpdated", Target: "jwr@lunar.app", Detail: "BiAnalyst"})
+}
+
+func Test_Log(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var buffer bytes.Buffer
+	log, err := NewLog(NewWriterSink(&buffer), logrtesting.NullLogger{})
+	assert.NoError(err)
+
+	writeRecords(log)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(lines, 3)
+
+	var records []Record
+	for _, line := range lines {
+		var record Record
+		assert.NoError(json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	assert.Equal(uint64(1), records[0].Sequence)
+	assert.Equal("", records[0].PreviousHash, "the first record starts the chain")
+	assert.Equal(records[0].Hash, records[1].PreviousHash)
+	assert.Equal(records[1].Hash, records[2].PreviousHash)
+	assert.Equal(int64(7), records[1].Generation)
+	assert.Equal("commit 3f2a1c", records[1].Cause)
+	assert.Equal(Failed, records[1].Result)
+	assert.Equal("access denied", records[1].Error)
+	assert.Equal(Succeeded, records[2].Result)
+
+	count, err := Verify(strings.NewReader(buffer.String()))
+	assert.NoError(err)
+	assert.Equal(3, count)
+
+	tampered := strings.Replace(buffer.String(), `"result":"Failed"`, `"result":"Succeeded"`, 1)
+	_, err = Verify(strings.NewReader(tampered))
+	assert.EqualError(err, "record 2 has been changed")
+
+	removed := lines[0] + "\n" + lines[2] + "\n"
+	_, err = Verify(strings.NewReader(removed))
+	assert.EqualError(err, "the chain is broken between record 1 and record 3")
+}
+
+func Test_Log_Nil(t *testing.T) {
+
+	var log *Log
+	log.SetContext(Context{Resource: "datascience/hubble"})
+	log.Record(Entry{System: "redshift", Action: "CreateUser", Target: "jwr"})
+	log.Reported(report.New())
+}
+
+func Test_Log_Reported(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sink := &failingSink{}
+	log, err := NewLog(sink, logrtesting.NullLogger{})
+	assert.NoError(err)
+
+	applyReport := report.New()
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "GrantAccess", Target: "bianalyst->lunar(read)", Cluster: "dev", Database: "jwr", Detail: "read on schema lunar", Outcome: report.Succeeded})
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "AddToGroup", Target: "jwr_bianalyst->bianalyst", Cluster: "dev", Outcome: report.Skipped})
+	applyReport.Add(report.Operation{System: report.Iam, Type: "PolicyDeleted", Target: "jwr_bianalyst", Role: "BiAnalyst", Outcome: report.Failed, Err: fmt.Errorf("access denied")})
+
+	log.Reported(applyReport)
+	log.Reported(nil)
+
+	assert.Len(sink.records, 2, "skipped operations are not recorded")
+	assert.Equal("dev/jwr", sink.records[0].Location)
+	assert.Equal("read on schema lunar", sink.records[0].Detail)
+	assert.Equal(Succeeded, sink.records[0].Result)
+	assert.Equal("BiAnalyst", sink.records[1].Location)
+	assert.Equal(Failed, sink.records[1].Result)
+	assert.Equal("access denied", sink.records[1].Error)
+}
+
+type failingSink struct {
+	fail    bool
+	records []Record
+}
+
+func (s *failingSink) Write(record Record) error {
+	if s.fail {
+		return fmt.Errorf("disk full")
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func Test_Log_WriteFailure(t *testing.T) {
+
+	assert := assert.New(t)
+
+	sink := &failingSink{}
+	log, err := NewLog(sink, logrtesting.NullLogger{})
+	assert.NoError(err)
+
+	log.Record(Entry{System: "redshift", Action: "CreateUser", Target: "jwr"})
+	sink.fail = true
+	log.Record(Entry{System: "redshift", Action: "DropUser", Target: "jwr"})
+	log.Record(Entry{System: "redshift", Action: "CreateUser", Target: "nra"})
+
+	assert.Len(sink.records, 1)
+	err = log.Flush()
+	assert.True(errors.Is(err, ErrUnwritten), "the records that aren't written are reported")
+	assert.Contains(err.Error(), "2 are waiting to be written")
+
+	sink.fail = false
+	assert.NoError(log.Flush())
+
+	assert.Len(sink.records, 3, "the records are written once the sink recovers")
+	assert.Equal("DropUser", sink.records[1].Action)
+	assert.Equal(sink.records[0].Hash, sink.records[1].PreviousHash)
+	assert.Equal(sink.records[1].Hash, sink.records[2].PreviousHash)
+	assert.Equal(uint64(3), sink.records[2].Sequence)
+
+	var nilLog *Log
+	assert.NoError(nilLog.Flush())
+}
+
+func Test_FileSink(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	sink, err := NewFileSink(path)
+	assert.NoError(err)
+	log, err := NewLog(sink, logrtesting.NullLogger{})
+	assert.NoError(err)
+	writeRecords(log)
+	assert.NoError(sink.Close())
+
+	sink, err = NewFileSink(path)
+	assert.NoError(err)
+	log, err = NewLog(sink, logrtesting.NullLogger{})
+	assert.NoError(err)
+	log.Record(Entry{System: "redshift", Action: "DropUser", Target: "jwr"})
+	assert.NoError(sink.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(err)
+	defer file.Close()
+
+	count, err := Verify(file)
+	assert.NoError(err, "the chain is continued after a restart")
+	assert.Equal(4, count)
+}
+
+func Test_HttpSink(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var received []Record
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var record Record
+		assert.NoError(json.NewDecoder(r.Body).Decode(&record))
+		received = append(received, record)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(server.URL)
+	assert.NoError(err)
+
+	assert.NoError(sink.Write(Record{Sequence: 1, System: "google", Action: "RolesUpdated", Target: "jwr@lunar.app"}))
+	assert.Len(received, 1)
+	assert.Equal("jwr@lunar.app", received[0].Target)
+
+	status = http.StatusInternalServerError
+	assert.Error(sink.Write(Record{Sequence: 2}), "the write fails unless the endpoint answers with a 2xx status")
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriterSink writes the records as JSON lines, e.g. to stdout.
+type WriterSink struct {
+	lock   sync.Mutex
+	writer io.Writer
+}
+
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+func (s *WriterSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.writer.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends the records to a file as JSON lines. Every record is synced to disk before Write returns.
+type FileSink struct {
+	lock sync.Mutex
+	path string
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log %s: %w", path, err)
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+func (s *FileSink) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+//Returns the last record in the file, or nil if the file is empty.
+func (s *FileSink) Last() (*Record, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+
+	var record Record
+	err = json.Unmarshal(last, &record)
+	if err != nil {
+		return nil, fmt.Errorf("the last line of %s is not an audit record: %w", s.path, err)
+	}
+	return &record, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HttpSink posts every record as JSON to an endpoint, which must answer with a 2xx status.
+type HttpSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHttpSink(url string) *HttpSink {
+	return &HttpSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *HttpSink) Write(record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	response, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint %s answered %s", s.url, response.Status)
+	}
+	return nil
+}
+
+//Returns the sink for the given destination, which is either stdout, an http(s) URL or the path of a file.
+func NewSink(destination string) (Sink, error) {
+	switch {
+	case destination == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(destination, "http://") || strings.HasPrefix(destination, "https://"):
+		return NewHttpSink(destination), nil
+	default:
+		return NewFileSink(destination)
+	}
+}
+
+//Checks that the JSON lines read from the reader form an unbroken chain and returns the number of records.
+func Verify(reader io.Reader) (int, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	count := 0
+	var previous *Record
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record Record
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return count, fmt.Errorf("line %d is not an audit record: %w", count+1, err)
+		}
+
+		hash, err := record.computeHash()
+		if err != nil {
+			return count, err
+		}
+		if hash != record.Hash {
+			return count, fmt.Errorf("record %d has been changed", record.Sequence)
+		}
+		if previous != nil && (record.PreviousHash != previous.Hash || record.Sequence != previous.Sequence+1) {
+			return count, fmt.Errorf("the chain is broken between record %d and record %d", previous.Sequence, record.Sequence)
+		}
+
+		previous = &record
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -3,8 +3,26 @@ package google
 import (
 	"fmt"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"sort"
+	"strings"
+	"time"
 )
 
+// RoleChange describes how the roles managed by the controller would be changed for a user.
+type RoleChange struct {
+	Email                  string
+	Added                  []string //the role ARN and identity provider ARN of each role, separated by a comma
+	Removed                []string
+	CurrentSessionDuration int //in seconds
+	DesiredSessionDuration int
+}
+
+func (c RoleChange) Changed() bool {
+	return len(c.Added) > 0 || len(c.Removed) > 0 || c.CurrentSessionDuration != c.DesiredSessionDuration
+}
+
 type Applier struct {
 	client *Client
 }
@@ -22,27 +40,140 @@ func (applier *Applier) userByEmail(users []User, email string) *User {
 	return nil
 }
 
-func (applier *Applier) Apply(model google.Model) error {
+//Returns the identity provider of the model and the session duration of the user, falling back to the defaults if they are not set.
+func (applier *Applier) loginSettings(model google.Model, user *google.User) (string, time.Duration) {
+	identityProvider, sessionDuration := model.IdentityProvider, user.SessionDuration
+	if identityProvider == "" {
+		identityProvider = iam.DefaultIdentityProvider().Name
+	}
+	if sessionDuration == 0 {
+		sessionDuration = iam.DefaultIdentityProvider().DefaultSessionDuration
+	}
+	return identityProvider, sessionDuration
+}
+
+//Adds the update of the roles of a user to the report, with the roles assigned to the user as the detail.
+func (applier *Applier) rolesUpdated(result *report.ApplyReport, user *google.User, started time.Time, err error) {
+	outcome := report.Succeeded
+	if err != nil {
+		outcome = report.Failed
+	}
+	roles := user.AssignedTo()
+	sort.Strings(roles)
+	result.Add(report.Operation{System: report.Google, Type: "RolesUpdated", Target: user.Email, User: user.Email, Detail: strings.Join(roles, ", "), Outcome: outcome, Err: err, Duration: time.Since(started)})
+}
+
+//Returns the users whose roles have been updated, along with the user that failed if an error is returned.
+func (applier *Applier) Apply(model google.Model) (*report.ApplyReport, error) {
+
+	result := report.New()
 
 	googleUsers, err := applier.client.Users()
 
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve users: %w", err)
+		return result, fmt.Errorf("Unable to retrieve users: %w", err)
 	}
 
 	for _, user := range model.Users {
 		googleUser := applier.userByEmail(googleUsers, user.Email)
+		started := time.Now()
 
 		if googleUser != nil {
-			err := applier.client.UpdateRoles(googleUser.Id, user.AssignedTo())
+			identityProvider, sessionDuration := applier.loginSettings(model, user)
+
+			changed, err := applier.client.RolesChanged(googleUser.Id, user.AssignedTo(), identityProvider, sessionDuration)
+
+			if err != nil {
+				err = fmt.Errorf("Unable to retrieve roles: %w", err)
+				applier.rolesUpdated(result, user, started, err)
+				return result, err
+			}
+			if !changed {
+				continue
+			}
+
+			err = applier.client.UpdateRoles(googleUser.Id, user.AssignedTo(), identityProvider, sessionDuration)
 
 			if err != nil {
-				return fmt.Errorf("Unable to update roles: %w", err)
+				err = fmt.Errorf("Unable to update roles: %w", err)
+				applier.rolesUpdated(result, user, started, err)
+				return result, err
 			}
+			applier.rolesUpdated(result, user, started, nil)
 		} else {
-			return fmt.Errorf("user %s doesn't exist", user.Email)
+			err := fmt.Errorf("user %s doesn't exist", user.Email)
+			applier.rolesUpdated(result, user, started, err)
+			return result, err
 		}
 	}
 
-	return nil
+	return result, nil
+}
+
+//Compares the roles of the users in the model with their roles in google without changing anything.
+func (applier *Applier) DetectDrift(model google.Model) ([]string, error) {
+
+	googleUsers, err := applier.client.Users()
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve users: %w", err)
+	}
+
+	var result []string
+
+	for _, user := range model.Users {
+		googleUser := applier.userByEmail(googleUsers, user.Email)
+
+		if googleUser == nil {
+			result = append(result, fmt.Sprintf("user %s doesn't exist", user.Email))
+			continue
+		}
+
+		identityProvider, sessionDuration := applier.loginSettings(model, user)
+
+		changed, err := applier.client.RolesChanged(googleUser.Id, user.AssignedTo(), identityProvider, sessionDuration)
+
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve roles: %w", err)
+		}
+		if changed {
+			result = append(result, fmt.Sprintf("the roles of user %s differ", user.Email))
+		}
+	}
+
+	return result, nil
+}
+
+//Returns the role changes Apply would make, without making them.
+func (applier *Applier) Plan(model google.Model) ([]RoleChange, error) {
+
+	googleUsers, err := applier.client.Users()
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve users: %w", err)
+	}
+
+	var result []RoleChange
+
+	for _, user := range model.Users {
+		googleUser := applier.userByEmail(googleUsers, user.Email)
+
+		if googleUser == nil {
+			return nil, fmt.Errorf("user %s doesn't exist", user.Email)
+		}
+
+		identityProvider, sessionDuration := applier.loginSettings(model, user)
+
+		change, err := applier.client.RoleChange(googleUser.Id, user.AssignedTo(), identityProvider, sessionDuration)
+
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve roles: %w", err)
+		}
+		if change.Changed() {
+			change.Email = user.Email
+			result = append(result, change)
+		}
+	}
+
+	return result, nil
 }
@@ -9,6 +9,8 @@ import (
 	admin "google.golang.org/api/admin/directory/v1"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
+	"sort"
+	"time"
 )
 
 type Client struct {
@@ -77,7 +79,9 @@ func (client *Client) Roles(userId string) ([]string, error) {
 	return result, err
 }
 
-func (client *Client) UpdateRoles(userId string, roles []string) error {
+//Replaces the roles managed by the controller that the user can log into through the identity provider.
+//The session duration applies to all roles of the user, including the ones that are not managed by the controller.
+func (client *Client) UpdateRoles(userId string, roles []string, identityProvider string, sessionDuration time.Duration) error {
 	currentRoles, err := client.get(userId)
 
 	if err != nil {
@@ -86,7 +90,7 @@ func (client *Client) UpdateRoles(userId string, roles []string) error {
 
 	currentRoles = currentRoles.Distinct()
 
-	desiredRoles := client.createDTO(roles)
+	desiredRoles := client.createDTO(roles, identityProvider, sessionDuration)
 
 	for _, r := range currentRoles.Roles {
 		if !r.isManaged(client.awsAccountId) {
@@ -97,6 +101,59 @@ func (client *Client) UpdateRoles(userId string, roles []string) error {
 	return client.update(userId, desiredRoles)
 }
 
+//Returns true if the roles managed by the controller or the session duration of the user differ from the given ones.
+func (client *Client) RolesChanged(userId string, roles []string, identityProvider string, sessionDuration time.Duration) (bool, error) {
+	change, err := client.RoleChange(userId, roles, identityProvider, sessionDuration)
+
+	if err != nil {
+		return false, err
+	}
+
+	return change.Changed(), nil
+}
+
+//Compares the roles managed by the controller and the session duration of the user with the given ones.
+func (client *Client) RoleChange(userId string, roles []string, identityProvider string, sessionDuration time.Duration) (RoleChange, error) {
+	currentRoles, err := client.get(userId)
+
+	if err != nil {
+		return RoleChange{}, err
+	}
+
+	desiredRoles := client.createDTO(roles, identityProvider, sessionDuration)
+
+	current := make(map[string]bool)
+	for _, r := range currentRoles.Roles {
+		if r.isManaged(client.awsAccountId) {
+			current[r.Value] = true
+		}
+	}
+
+	desired := make(map[string]bool)
+	for _, r := range desiredRoles.Roles {
+		desired[r.Value] = true
+	}
+
+	result := RoleChange{
+		CurrentSessionDuration: currentRoles.SessionDuration,
+		DesiredSessionDuration: desiredRoles.SessionDuration,
+	}
+	for value := range desired {
+		if !current[value] {
+			result.Added = append(result.Added, value)
+		}
+	}
+	for value := range current {
+		if !desired[value] {
+			result.Removed = append(result.Removed, value)
+		}
+	}
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+
+	return result, nil
+}
+
 func (client *Client) get(userKey string) (AwsRolesCustomSchemaDTO, error) {
 
 	var result AwsRolesCustomSchemaDTO
@@ -143,20 +200,20 @@ func (client *Client) update(userKey string, awsRoles AwsRolesCustomSchemaDTO) e
 	return nil
 }
 
-func (client *Client) createDTO(roles []string) AwsRolesCustomSchemaDTO {
+func (client *Client) createDTO(roles []string, identityProvider string, sessionDuration time.Duration) AwsRolesCustomSchemaDTO {
 
 	var awsRoles []AwsRoleCustomSchemaDTO
 
 	for _, role := range roles {
 		awsRole := AwsRoleCustomSchemaDTO{
 			Type:  "work",
-			Value: fmt.Sprintf("arn:aws:iam::%s:role/hubble-rbac/%s,arn:aws:iam::%s:saml-provider/GoogleApps", client.awsAccountId, role, client.awsAccountId),
+			Value: fmt.Sprintf("arn:aws:iam::%s:role/hubble-rbac/%s,arn:aws:iam::%s:saml-provider/%s", client.awsAccountId, role, client.awsAccountId, identityProvider),
 		}
 		awsRoles = append(awsRoles, awsRole)
 	}
 	return AwsRolesCustomSchemaDTO{
 		Roles:           awsRoles,
-		SessionDuration: 14400,
+		SessionDuration: int(sessionDuration.Seconds()),
 	}
 }
 
@@ -1,6 +1,9 @@
 package google
 
-import "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
 
 type NoOpApplier struct {
 }
@@ -9,6 +12,14 @@ func NewNoOpApplier() *NoOpApplier {
 	return &NoOpApplier{}
 }
 
-func (applier *NoOpApplier) Apply(model google.Model) error {
-	return nil
+func (applier *NoOpApplier) Apply(model google.Model) (*report.ApplyReport, error) {
+	return report.New(), nil
+}
+
+func (applier *NoOpApplier) DetectDrift(model google.Model) ([]string, error) {
+	return nil, nil
+}
+
+func (applier *NoOpApplier) Plan(model google.Model) ([]RoleChange, error) {
+	return nil, nil
 }
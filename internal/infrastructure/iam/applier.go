@@ -1,11 +1,14 @@
 package iam
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/go-logr/logr"
 	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
 	"strings"
+	"time"
 )
 
 type ApplyEventType int
@@ -38,28 +41,33 @@ func (t ApplyEventType) ToString() string {
 	}
 }
 
-type ApplyEventLister interface {
-	Handle(eventType ApplyEventType, name string)
-}
-
 type Applier struct {
-	accountId     string
-	region        string
-	client        *Client
-	eventListener ApplyEventLister
-	logger        logr.Logger
+	accountId string
+	region    string
+	client    *Client
+	logger    logr.Logger
 }
 
-func NewApplier(client *Client, accountId string, region string, eventListener ApplyEventLister, logger logr.Logger) *Applier {
+func NewApplier(client *Client, accountId string, region string, logger logr.Logger) *Applier {
 	return &Applier{
-		accountId:     accountId,
-		region:        region,
-		client:        client,
-		eventListener: eventListener,
-		logger:        logger,
+		accountId: accountId,
+		region:    region,
+		client:    client,
+		logger:    logger,
 	}
 }
 
+//Adds a change that has been made to the report.
+func (applier *Applier) changed(result *report.ApplyReport, eventType ApplyEventType, name string, role string, started time.Time) {
+	result.Add(report.Operation{System: report.Iam, Type: eventType.ToString(), Target: name, Role: role, Outcome: report.Succeeded, Duration: time.Since(started)})
+}
+
+//Adds a change that could not be made to the report and returns the error.
+func (applier *Applier) failed(result *report.ApplyReport, eventType ApplyEventType, name string, role string, started time.Time, err error) error {
+	result.Add(report.Operation{System: report.Iam, Type: eventType.ToString(), Target: name, Role: role, Outcome: report.Failed, Err: err, Duration: time.Since(started)})
+	return err
+}
+
 //TODO: replace all this Sprintf'ing with go templating!
 func (applier *Applier) buildDatabaseLoginPolicyDocument(policy *iamCore.DatabaseLoginPolicy) string {
 
@@ -157,16 +165,106 @@ func (applier *Applier) createAndAttachPolicy(role *iam.Role, name string, docum
 	return nil
 }
 
-func (applier *Applier) createRole(name string) (*iam.Role, error) {
-	return applier.client.CreateOrUpdateLoginRole(name, applier.accountId)
+//The managed policy with the inline policy statements of a role. Database usernames can not contain dashes, so it never has the name of a database login policy.
+func (applier *Applier) customPolicyName(role *iamCore.AwsRole) string {
+	return fmt.Sprintf("%s-custom", role.Name)
+}
+
+func (applier *Applier) buildCustomPolicyDocument(statements []*iamCore.PolicyStatement) (string, error) {
+
+	type statementDocument struct {
+		Sid      string   `json:"Sid,omitempty"`
+		Effect   string   `json:"Effect"`
+		Action   []string `json:"Action"`
+		Resource []string `json:"Resource"`
+	}
+
+	type policyDocument struct {
+		Version   string              `json:"Version"`
+		Statement []statementDocument `json:"Statement"`
+	}
+
+	document := policyDocument{Version: "2012-10-17"}
+	for _, statement := range statements {
+		document.Statement = append(document.Statement, statementDocument{
+			Sid:      statement.Sid,
+			Effect:   statement.Effect,
+			Action:   statement.Actions,
+			Resource: statement.Resources,
+		})
+	}
+
+	//whitespace counts towards the size limit of managed policies, so the document is not indented
+	result, err := json.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
 }
 
-func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string) error {
+//Creates the policy and attaches it to the role if it is not attached, or replaces it if the document has changed.
+//Returns true if the policy was created or replaced.
+func (applier *Applier) createOrUpdatePolicy(currentRole *iam.Role, attachedPolicy *iam.AttachedPolicy, policyName string, desiredPolicyDocument string, policyDocuments map[string]string, result *report.ApplyReport) (bool, error) {
+	started := time.Now()
+	if attachedPolicy != nil {
+		if desiredPolicyDocument == policyDocuments[policyName] {
+			applier.logger.Info(fmt.Sprintf("No changes detected in policy %s", policyName))
+		} else {
+			applier.logger.Info(fmt.Sprintf("Updating policy %s attached to %s", policyName, *currentRole.RoleName))
+
+			err := applier.detachAndDeletePolicy(currentRole, attachedPolicy)
+			if err != nil {
+				return false, applier.failed(result, PolicyUpdated, policyName, *currentRole.RoleName, started, fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err))
+			}
+
+			err = applier.createAndAttachPolicy(currentRole, policyName, desiredPolicyDocument)
+			if err != nil {
+				return false, applier.failed(result, PolicyUpdated, policyName, *currentRole.RoleName, started, fmt.Errorf("unable to create and attach policy %s: %w", policyName, err))
+			}
+			applier.changed(result, PolicyUpdated, policyName, *currentRole.RoleName, started)
+			return true, nil
+		}
+	} else {
+		applier.logger.Info(fmt.Sprintf("Creating policy %s and attaching to %s", policyName, *currentRole.RoleName))
+		err := applier.createAndAttachPolicy(currentRole, policyName, desiredPolicyDocument)
+
+		if err != nil {
+			return false, applier.failed(result, PolicyCreated, policyName, *currentRole.RoleName, started, fmt.Errorf("unable to create and attach policy %s: %w", policyName, err))
+		}
+		applier.changed(result, PolicyCreated, policyName, *currentRole.RoleName, started)
+		return true, nil
+	}
+	return false, nil
+}
+
+//Returns the identity provider and session duration of the role, using the defaults for those that are not set.
+func (applier *Applier) loginSettings(role *iamCore.AwsRole) (string, time.Duration) {
+	defaults := iamCore.DefaultIdentityProvider()
+	identityProvider := role.IdentityProvider
+	if identityProvider == "" {
+		identityProvider = defaults.Name
+	}
+	sessionDuration := role.SessionDuration
+	if sessionDuration == 0 {
+		sessionDuration = defaults.DefaultSessionDuration
+	}
+	return identityProvider, sessionDuration
+}
+
+func (applier *Applier) createRole(role *iamCore.AwsRole) (*iam.Role, error) {
+	identityProvider, sessionDuration := applier.loginSettings(role)
+	return applier.client.CreateOrUpdateLoginRole(role.Name, applier.accountId, identityProvider, sessionDuration)
+}
+
+//Attaches and detaches the policies of the role. Returns true if any policy was attached, detached, created, replaced or deleted.
+func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string, result *report.ApplyReport) (bool, error) {
+
+	changed := false
 
 	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(currentRole)
 
 	if err != nil {
-		return fmt.Errorf("unable to list attached policies: %w", err)
+		return false, fmt.Errorf("unable to list attached policies: %w", err)
 	}
 
 	for _, desiredPolicy := range desiredRole.Policies {
@@ -175,18 +273,19 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			policy, err := applier.client.lookupPolicyByArn(desiredPolicy.Arn)
 
 			if err != nil {
-				return fmt.Errorf("unable to fetch policy: %w", err)
+				return false, fmt.Errorf("unable to fetch policy: %w", err)
 			}
 
 			if policy == nil {
-				return fmt.Errorf("referenced policy with Arn %s does not exist", desiredPolicy.Arn)
+				return false, fmt.Errorf("referenced policy with Arn %s does not exist", desiredPolicy.Arn)
 			}
 
 			err = applier.client.attachPolicy(currentRole, policy)
 
 			if err != nil {
-				return fmt.Errorf("failed attaching policy %s: %w", desiredPolicy.Arn, err)
+				return false, fmt.Errorf("failed attaching policy %s: %w", desiredPolicy.Arn, err)
 			}
+			changed = true
 		}
 	}
 
@@ -200,52 +299,54 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			if attachedPolicy != nil {
 				applier.logger.Info(fmt.Sprintf("Deleting policy %s attached to %s", policyName, *currentRole.RoleName))
 
+				started := time.Now()
 				err := applier.detachAndDeletePolicy(currentRole, attachedPolicy)
 				if err != nil {
-					return fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err)
+					return false, applier.failed(result, PolicyDeleted, policyName, *currentRole.RoleName, started, fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err))
 				}
-				applier.eventListener.Handle(PolicyDeleted, policyName)
+				applier.changed(result, PolicyDeleted, policyName, *currentRole.RoleName, started)
+				changed = true
 			}
 		} else {
-			if attachedPolicy != nil {
-				if desiredPolicyDocument == policyDocuments[policyName] {
-					applier.logger.Info(fmt.Sprintf("No changes detected in policy %s", policyName))
-				} else {
-					applier.logger.Info(fmt.Sprintf("Updating policy %s attached to %s", policyName, *currentRole.RoleName))
-
-					err := applier.detachAndDeletePolicy(currentRole, attachedPolicy)
-					if err != nil {
-						return fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err)
-					}
-
-					err = applier.createAndAttachPolicy(currentRole, policyName, desiredPolicyDocument)
-					if err != nil {
-						return fmt.Errorf("unable to create and attach policy %s: %w", policyName, err)
-					}
-					applier.eventListener.Handle(PolicyUpdated, policyName)
-				}
-			} else {
-				applier.logger.Info(fmt.Sprintf("Creating policy %s and attaching to %s", policyName, *currentRole.RoleName))
-				err := applier.createAndAttachPolicy(currentRole, policyName, desiredPolicyDocument)
-
-				if err != nil {
-					return fmt.Errorf("unable to create and attach policy %s: %w", policyName, err)
-				}
-				applier.eventListener.Handle(PolicyCreated, policyName)
+			policyChanged, err := applier.createOrUpdatePolicy(currentRole, attachedPolicy, policyName, desiredPolicyDocument, policyDocuments, result)
+			if err != nil {
+				return false, err
 			}
+			changed = changed || policyChanged
 		}
 	}
 
+	customPolicyName := applier.customPolicyName(desiredRole)
+
+	if len(desiredRole.Statements) > 0 {
+		desiredPolicyDocument, err := applier.buildCustomPolicyDocument(desiredRole.Statements)
+		if err != nil {
+			return false, fmt.Errorf("unable to render the policy statements of role %s: %w", desiredRole.Name, err)
+		}
+		attachedPolicy := applier.client.lookupAttachedPolicy(attachedPolicies, customPolicyName)
+
+		policyChanged, err := applier.createOrUpdatePolicy(currentRole, attachedPolicy, customPolicyName, desiredPolicyDocument, policyDocuments, result)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || policyChanged
+	}
+
 	for _, attachedPolicy := range attachedPolicies {
+		if len(desiredRole.Statements) > 0 && *attachedPolicy.PolicyName == customPolicyName {
+			continue
+		}
 		if desiredRole.LookupDatabaseLoginPolicyForUsername(*attachedPolicy.PolicyName) == nil {
 			applier.logger.Info(fmt.Sprintf("Deleting policy %s attached to %s", *attachedPolicy.PolicyName, *currentRole.RoleName))
 
+			started := time.Now()
 			err = applier.detachAndDeletePolicy(currentRole, attachedPolicy)
 
 			if err != nil {
-				return fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err)
+				return false, applier.failed(result, PolicyDeleted, *attachedPolicy.PolicyName, *currentRole.RoleName, started, fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err))
 			}
-			applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+			applier.changed(result, PolicyDeleted, *attachedPolicy.PolicyName, *currentRole.RoleName, started)
+			changed = true
 		}
 	}
 
@@ -257,15 +358,16 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			err := applier.client.DetachUnmanagedPolicy(currentRole, attachedPolicy)
 
 			if err != nil {
-				return fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err)
+				return false, fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err)
 			}
+			changed = true
 		}
 	}
 
-	return nil
+	return changed, nil
 }
 
-func (applier *Applier) deleteRole(role *iam.Role) error {
+func (applier *Applier) deleteRole(role *iam.Role, result *report.ApplyReport) error {
 
 	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(role)
 
@@ -276,13 +378,14 @@ func (applier *Applier) deleteRole(role *iam.Role) error {
 	for _, attachedPolicy := range attachedPolicies {
 		applier.logger.Info(fmt.Sprintf("Deleting policy %s attached to %s", *attachedPolicy.PolicyName, *role.RoleName))
 
+		started := time.Now()
 		err = applier.detachAndDeletePolicy(role, attachedPolicy)
 
 		if err != nil {
-			return err
+			return applier.failed(result, PolicyDeleted, *attachedPolicy.PolicyName, *role.RoleName, started, err)
 		}
 
-		applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+		applier.changed(result, PolicyDeleted, *attachedPolicy.PolicyName, *role.RoleName, started)
 	}
 
 	attachedPolicies, err = applier.client.ListUnmanagedAttachedPolicies(role)
@@ -290,65 +393,89 @@ func (applier *Applier) deleteRole(role *iam.Role) error {
 	for _, attachedPolicy := range attachedPolicies {
 		applier.logger.Info(fmt.Sprintf("Detaching policy %s attached to %s", *attachedPolicy.PolicyName, *role.RoleName))
 
+		started := time.Now()
 		err := applier.client.DetachUnmanagedPolicy(role, attachedPolicy)
 
 		if err != nil {
-			return fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err)
+			return applier.failed(result, PolicyDeleted, *attachedPolicy.PolicyName, *role.RoleName, started, fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err))
 		}
-		applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+		applier.changed(result, PolicyDeleted, *attachedPolicy.PolicyName, *role.RoleName, started)
 	}
 
 	return applier.client.DeleteLoginRole(role)
 }
 
-func (applier *Applier) Apply(model iamCore.Model) error {
+//Returns the roles and policies that have been changed, along with the change that failed if an error is returned.
+func (applier *Applier) Apply(model iamCore.Model) (*report.ApplyReport, error) {
+
+	result := report.New()
 
 	policyDocuments, err := applier.client.GetPolicyDocuments()
 
 	if err != nil {
-		return fmt.Errorf("unable to list policy documents: %w", err)
+		return result, fmt.Errorf("unable to list policy documents: %w", err)
 	}
 
 	existingRoles, err := applier.client.ListRoles()
 
 	if err != nil {
-		return fmt.Errorf("unable to list roles: %w", err)
+		return result, fmt.Errorf("unable to list roles: %w", err)
 	}
 
 	for _, desiredRole := range model.Roles {
 		var err error
 
 		existingRole := applier.lookupRole(existingRoles, desiredRole.Name)
+		loginRoleUpdated := false
 
 		if existingRole == nil {
 			applier.logger.Info(fmt.Sprintf("Creating role %s", desiredRole.Name))
-			existingRole, err = applier.createRole(desiredRole.Name)
+			started := time.Now()
+			existingRole, err = applier.createRole(desiredRole)
 
 			if err != nil {
-				return fmt.Errorf("failed when creating role %s: %w", desiredRole.Name, err)
+				return result, applier.failed(result, RoleCreated, desiredRole.Name, desiredRole.Name, started, fmt.Errorf("failed when creating role %s: %w", desiredRole.Name, err))
+			}
+			applier.changed(result, RoleCreated, desiredRole.Name, desiredRole.Name, started)
+		} else {
+			identityProvider, sessionDuration := applier.loginSettings(desiredRole)
+
+			if applier.client.LoginRoleChanged(existingRole, applier.accountId, identityProvider, sessionDuration) {
+				applier.logger.Info(fmt.Sprintf("Updating the identity provider and session duration of role %s", desiredRole.Name))
+
+				started := time.Now()
+				err = applier.client.UpdateLoginRole(existingRole, applier.accountId, identityProvider, sessionDuration)
+				if err != nil {
+					return result, applier.failed(result, RoleUpdated, desiredRole.Name, desiredRole.Name, started, fmt.Errorf("failed when updating role %s: %w", desiredRole.Name, err))
+				}
+				loginRoleUpdated = true
 			}
-			applier.eventListener.Handle(RoleCreated, desiredRole.Name)
 		}
 
 		applier.logger.Info(fmt.Sprintf("Updating role %s", desiredRole.Name))
-		err = applier.updateRole(desiredRole, existingRole, policyDocuments)
+		started := time.Now()
+		policiesChanged, err := applier.updateRole(desiredRole, existingRole, policyDocuments, result)
 		if err != nil {
-			return fmt.Errorf("failed when updating role %s: %w", desiredRole.Name, err)
+			return result, applier.failed(result, RoleUpdated, desiredRole.Name, desiredRole.Name, started, fmt.Errorf("failed when updating role %s: %w", desiredRole.Name, err))
+		}
+		//roles that are already in sync are not recorded, so a resync does not report changes that were never made
+		if loginRoleUpdated || policiesChanged {
+			applier.changed(result, RoleUpdated, desiredRole.Name, desiredRole.Name, started)
 		}
-		applier.eventListener.Handle(RoleUpdated, desiredRole.Name)
 	}
 
 	for _, existingRole := range existingRoles {
 		if model.LookupRole(*existingRole.RoleName) == nil {
 			applier.logger.Info(fmt.Sprintf("Deleting role %s", *existingRole.RoleName))
-			err = applier.deleteRole(existingRole)
+			started := time.Now()
+			err = applier.deleteRole(existingRole, result)
 
 			if err != nil {
-				return fmt.Errorf("failed when deleting role %s: %w", *existingRole.RoleName, err)
+				return result, applier.failed(result, RoleDeleted, *existingRole.RoleName, *existingRole.RoleName, started, fmt.Errorf("failed when deleting role %s: %w", *existingRole.RoleName, err))
 			}
-			applier.eventListener.Handle(RoleDeleted, *existingRole.RoleName)
+			applier.changed(result, RoleDeleted, *existingRole.RoleName, *existingRole.RoleName, started)
 		}
 	}
 
-	return nil
+	return result, nil
 }
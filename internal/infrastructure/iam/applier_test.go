@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -61,19 +62,26 @@ func (client *Client) createUnmanagedPolicy(name string, document string) (*iam.
 	return createPolicyResponse.Policy, nil
 }
 
+//Applies the model and records the changes it made.
+func (context TestContext) apply(model iamCore.Model) (*report.ApplyReport, error) {
+	applyReport, err := context.applier.Apply(model)
+	context.eventRecorder.Record(applyReport)
+	return applyReport, err
+}
+
 func setUp(t *testing.T) TestContext {
 
 	session := LocalStackSessionFactory{}.CreateSession()
 	iamClient := New(session)
 	eventRecorder := EventRecorder{}
 	logger := infrastructure.NewLogger(t)
-	applier := NewApplier(iamClient, accountId, region, &eventRecorder, logger)
+	applier := NewApplier(iamClient, accountId, region, logger)
 
 	roles, err := iamClient.ListRoles()
 	failOnError(err)
 
 	for _, role := range roles {
-		err = applier.deleteRole(role)
+		err = applier.deleteRole(role, report.New())
 		failOnError(err)
 	}
 
@@ -100,7 +108,7 @@ func TestApplier_NoRoles(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
 	assert.NoError(err)
 
 	actual := FetchIAMState(context.client)
@@ -117,7 +125,7 @@ func TestApplier_SingleRole(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	model := iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -133,7 +141,9 @@ func TestApplier_SingleRole(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}
+
+	applyReport, err := context.apply(model)
 
 	assert.NoError(err)
 
@@ -144,6 +154,23 @@ func TestApplier_SingleRole(t *testing.T) {
 
 	assert.Equal(1, context.eventRecorder.Count(RoleCreated))
 	assert.Equal(1, context.eventRecorder.Count(PolicyCreated))
+
+	var operations []string
+	for _, operation := range applyReport.Operations {
+		operations = append(operations, operation.String())
+	}
+	assert.Equal([]string{
+		"RoleCreated(BiAnalyst) in iam BiAnalyst",
+		"PolicyCreated(jwr_bianalyst) in iam BiAnalyst",
+		"RoleUpdated(BiAnalyst) in iam BiAnalyst",
+	}, operations, "every change is reported")
+	assert.Equal(3, applyReport.Count(report.Succeeded))
+
+	applyReport, err = context.apply(model)
+
+	assert.NoError(err)
+	assert.Empty(applyReport.Operations, "a role that is already in sync is not reported as updated")
+	assert.Equal(1, context.eventRecorder.Count(RoleUpdated))
 }
 
 func TestApplier_SingleRoleTwoDatabases(t *testing.T) {
@@ -152,7 +179,7 @@ func TestApplier_SingleRoleTwoDatabases(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -191,7 +218,7 @@ func TestApplier_SingleRoleTwoUsers(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -236,7 +263,7 @@ func TestApplier_SingleRoleAddAnotherDatabase(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -256,7 +283,7 @@ func TestApplier_SingleRoleAddAnotherDatabase(t *testing.T) {
 
 	assert.NoError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -296,7 +323,7 @@ func TestApplier_RemoveUserWillRemoveAccess(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -321,7 +348,7 @@ func TestApplier_RemoveUserWillRemoveAccess(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst"}}
 	AssertState(assert, actual, expected, "IAM role has not attached policies")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name:                  "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{},
@@ -340,7 +367,7 @@ func TestApplier_RoleWithNoUsers(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name:                  "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{},
@@ -382,7 +409,7 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 	_, err := context.client.createUnmanagedPolicy("access-to-tmp-bucket", policyDocument)
 	failOnError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -408,7 +435,7 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst", "access-to-tmp-bucket"}}
 	AssertState(assert, actual, expected, "IAM role have been created")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -461,7 +488,7 @@ func TestApplier_UserWithReferencedUnmanagedPoliciesCanBeDeleted(t *testing.T) {
 	_, err := context.client.createUnmanagedPolicy("access-to-tmp-bucket", policyDocument)
 	failOnError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -487,10 +514,148 @@ func TestApplier_UserWithReferencedUnmanagedPoliciesCanBeDeleted(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst", "access-to-tmp-bucket"}}
 	AssertState(assert, actual, expected, "IAM role have been created")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
 	assert.NoError(err)
 
 	actual = FetchIAMState(context.client)
 	expected.Roles = map[string][]string{}
 	AssertState(assert, actual, expected, "IAM role has been deleted")
 }
+
+func TestApplier_RoleWithPolicyStatements(t *testing.T) {
+
+	context := setUp(t)
+
+	assert := assert.New(t)
+
+	role := &iamCore.AwsRole{
+		Name: "BiAnalyst",
+		Statements: []*iamCore.PolicyStatement{
+			{
+				Sid:       "ReadReports",
+				Effect:    "Allow",
+				Actions:   []string{"s3:GetObject"},
+				Resources: []string{"arn:aws:s3:::lunar-reports/*"},
+			},
+		},
+	}
+
+	_, err := context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{role}})
+	assert.NoError(err)
+
+	actual := FetchIAMState(context.client)
+	expected := IAMState{}
+	expected.Roles = map[string][]string{"BiAnalyst": {"BiAnalyst-custom"}}
+	AssertState(assert, actual, expected, "the policy statements are attached to the role")
+	assert.Equal(1, context.eventRecorder.Count(PolicyCreated))
+
+	context.eventRecorder.Reset()
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{role}})
+	assert.NoError(err)
+	assert.Equal(0, context.eventRecorder.CountAll(), "nothing happens if the statements are unchanged")
+
+	role.Statements[0].Actions = append(role.Statements[0].Actions, "s3:ListBucket")
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{role}})
+	assert.NoError(err)
+	assert.Equal(1, context.eventRecorder.Count(PolicyUpdated))
+
+	role.Statements = nil
+	_, err = context.apply(iamCore.Model{Roles: []*iamCore.AwsRole{role}})
+	assert.NoError(err)
+	assert.Equal(1, context.eventRecorder.Count(PolicyDeleted))
+
+	actual = FetchIAMState(context.client)
+	expected.Roles = map[string][]string{"BiAnalyst": {}}
+	AssertState(assert, actual, expected, "the policy is deleted when the role has no statements")
+}
+
+func TestApplier_DetectDrift(t *testing.T) {
+
+	context := setUp(t)
+
+	assert := assert.New(t)
+
+	model := iamCore.Model{Roles: []*iamCore.AwsRole{
+		{
+			Name: "BiAnalyst",
+			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
+				{
+					Email:            "jwr@lunar.app",
+					DatabaseUsername: "jwr_bianalyst",
+					Databases:        []*iamCore.Database{{ClusterIdentifier: "dev", Name: "jwr"}},
+				},
+			},
+		},
+	}}
+
+	drift, err := context.applier.DetectDrift(model)
+	assert.NoError(err)
+	assert.Equal([]string{"role BiAnalyst does not exist"}, drift)
+
+	_, err = context.apply(model)
+	assert.NoError(err)
+
+	context.eventRecorder.Reset()
+	drift, err = context.applier.DetectDrift(model)
+	assert.NoError(err)
+	assert.Empty(drift)
+	assert.Equal(0, context.eventRecorder.CountAll(), "nothing is changed when detecting drift")
+
+	model.Roles[0].DatabaseLoginPolicies[0].Databases = append(model.Roles[0].DatabaseLoginPolicies[0].Databases, &iamCore.Database{ClusterIdentifier: "dev", Name: "prod"})
+	drift, err = context.applier.DetectDrift(model)
+	assert.NoError(err)
+	assert.Equal([]string{"policy jwr_bianalyst attached to role BiAnalyst differs"}, drift)
+
+	drift, err = context.applier.DetectDrift(iamCore.Model{})
+	assert.NoError(err)
+	assert.Equal([]string{"role BiAnalyst is not declared"}, drift)
+}
+
+func TestApplier_Plan(t *testing.T) {
+
+	context := setUp(t)
+
+	assert := assert.New(t)
+
+	model := iamCore.Model{Roles: []*iamCore.AwsRole{
+		{
+			Name: "BiAnalyst",
+			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
+				{
+					Email:            "jwr@lunar.app",
+					DatabaseUsername: "jwr_bianalyst",
+					Databases:        []*iamCore.Database{{ClusterIdentifier: "dev", Name: "jwr"}},
+				},
+			},
+		},
+	}}
+
+	plan, err := context.applier.Plan(model)
+	assert.NoError(err)
+	assert.Len(plan, 2)
+	assert.Equal(Change{Type: RoleCreated, Name: "BiAnalyst", Description: "role BiAnalyst does not exist"}, plan[0])
+	assert.Equal(PolicyCreated, plan[1].Type)
+	assert.Equal("BiAnalyst", plan[1].Role)
+	assert.Contains(plan[1].Diff, "+++ jwr_bianalyst (desired)")
+	assert.Equal(0, context.eventRecorder.CountAll(), "nothing is changed when planning")
+
+	_, err = context.apply(model)
+	assert.NoError(err)
+
+	plan, err = context.applier.Plan(model)
+	assert.NoError(err)
+	assert.Empty(plan)
+
+	model.Roles[0].DatabaseLoginPolicies[0].Databases = append(model.Roles[0].DatabaseLoginPolicies[0].Databases, &iamCore.Database{ClusterIdentifier: "dev", Name: "prod"})
+	plan, err = context.applier.Plan(model)
+	assert.NoError(err)
+	assert.Len(plan, 1)
+	assert.Equal(PolicyUpdated, plan[0].Type)
+	assert.Contains(plan[0].Diff, "dbname:dev/prod")
+
+	plan, err = context.applier.Plan(iamCore.Model{})
+	assert.NoError(err)
+	assert.Len(plan, 2)
+	assert.Equal(RoleDeleted, plan[0].Type)
+	assert.Equal(PolicyDeleted, plan[1].Type)
+}
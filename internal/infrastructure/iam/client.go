@@ -6,7 +6,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	log "github.com/sirupsen/logrus"
+	"net/url"
 	"strings"
+	"time"
 )
 
 var iamPrefix = "/hubble-rbac/"
@@ -336,26 +338,8 @@ func (client *Client) DeleteAttachedPolicy(policy *iam.AttachedPolicy) error {
 	return client.deletePolicy(*policy.PolicyName, *policy.PolicyArn)
 }
 
-func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*iam.Role, error) {
-
-	c := iam.New(client.session)
-
-	roles, err := client.ListRoles()
-
-	if err != nil {
-		return nil, fmt.Errorf("unable to list roles: %w", err)
-	}
-
-	role := client.lookupRole(roles, name)
-	if role != nil {
-		err = client.DeleteLoginRole(role)
-		if err != nil {
-			return nil, fmt.Errorf("unable to delete login role %s: %w", name, err)
-		}
-	}
-
-	var maxSessionDuration int64 = 14400
-
+//The trust policy of a login role, which allows the users of the SAML identity provider to log into the role.
+func loginRoleTrustPolicy(accountId string, identityProvider string) string {
 	assumeRolePolicyDocument := `
 {
   "Version": "2012-10-17",
@@ -363,7 +347,7 @@ func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*i
     {
       "Effect": "Allow",
       "Principal": {
-        "Federated": "arn:aws:iam::%s:saml-provider/GoogleApps"
+        "Federated": "arn:aws:iam::%s:saml-provider/%s"
       },
       "Action": "sts:AssumeRoleWithSAML",
       "Condition": {
@@ -375,9 +359,31 @@ func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*i
   ]
 }
 `
+	return strings.TrimSpace(fmt.Sprintf(assumeRolePolicyDocument, accountId, identityProvider))
+}
+
+func (client *Client) CreateOrUpdateLoginRole(name string, accountId string, identityProvider string, sessionDuration time.Duration) (*iam.Role, error) {
+
+	c := iam.New(client.session)
+
+	roles, err := client.ListRoles()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	role := client.lookupRole(roles, name)
+	if role != nil {
+		err = client.DeleteLoginRole(role)
+		if err != nil {
+			return nil, fmt.Errorf("unable to delete login role %s: %w", name, err)
+		}
+	}
+
+	maxSessionDuration := int64(sessionDuration.Seconds())
 
 	response, err := c.CreateRole(&iam.CreateRoleInput{
-		AssumeRolePolicyDocument: aws.String(strings.TrimSpace(fmt.Sprintf(assumeRolePolicyDocument, accountId))),
+		AssumeRolePolicyDocument: aws.String(loginRoleTrustPolicy(accountId, identityProvider)),
 		Description:              aws.String("test"),
 		MaxSessionDuration:       &maxSessionDuration,
 		Path:                     &iamPrefix,
@@ -393,6 +399,48 @@ func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*i
 	return response.Role, nil
 }
 
+//Returns true if the login role does not trust the identity provider or does not have the given maximum session duration.
+func (client *Client) LoginRoleChanged(role *iam.Role, accountId string, identityProvider string, sessionDuration time.Duration) bool {
+	if role.MaxSessionDuration == nil || *role.MaxSessionDuration != int64(sessionDuration.Seconds()) {
+		return true
+	}
+	if role.AssumeRolePolicyDocument == nil {
+		return true
+	}
+	//the policy document is returned url encoded
+	document, err := url.QueryUnescape(*role.AssumeRolePolicyDocument)
+	if err != nil {
+		return true
+	}
+	return !strings.Contains(document, fmt.Sprintf("arn:aws:iam::%s:saml-provider/%s\"", accountId, identityProvider))
+}
+
+//Updates the trust policy and the maximum session duration of an existing login role.
+func (client *Client) UpdateLoginRole(role *iam.Role, accountId string, identityProvider string, sessionDuration time.Duration) error {
+
+	c := iam.New(client.session)
+
+	_, err := c.UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+		PolicyDocument: aws.String(loginRoleTrustPolicy(accountId, identityProvider)),
+		RoleName:       role.RoleName,
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to update the trust policy of login role %s: %w", *role.RoleName, err)
+	}
+
+	_, err = c.UpdateRole(&iam.UpdateRoleInput{
+		MaxSessionDuration: aws.Int64(int64(sessionDuration.Seconds())),
+		RoleName:           role.RoleName,
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to update the session duration of login role %s: %w", *role.RoleName, err)
+	}
+
+	return nil
+}
+
 func (client *Client) DeleteLoginRole(role *iam.Role) error {
 	c := iam.New(client.session)
 
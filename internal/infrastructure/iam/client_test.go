@@ -14,6 +14,9 @@ import (
 
 //YOU MUST RUN docker-compose up PRIOR TO RUNNING THIS TEST
 
+const identityProvider = "GoogleApps"
+const sessionDuration = 4 * time.Hour
+
 func init() {
 	log.SetOutput(os.Stdout)
 	log.SetLevel(log.InfoLevel)
@@ -107,7 +110,7 @@ func Test_AttachPolicy_Is_Idempotent(t *testing.T) {
 	session := LocalStackSessionFactory{}.CreateSession()
 	iamClient := New(session)
 
-	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId, identityProvider, sessionDuration)
 	assert.NoError(err)
 
 	document := `
@@ -151,7 +154,7 @@ func Test_DetachPolicy_Is_Idempotent(t *testing.T) {
 	session := LocalStackSessionFactory{}.CreateSession()
 	iamClient := New(session)
 
-	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId, identityProvider, sessionDuration)
 	assert.NoError(err)
 
 	document := `
@@ -201,10 +204,10 @@ func Test_CreateLoginRole_Is_Idempotent(t *testing.T) {
 	iamClient := New(session)
 
 	roleName := utils.GenerateRandomString(10)
-	_, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	_, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId, identityProvider, sessionDuration)
 	assert.NoError(err)
 
-	_, err = iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	_, err = iamClient.CreateOrUpdateLoginRole(roleName, accountId, identityProvider, sessionDuration)
 	assert.NoError(err)
 }
 
@@ -216,7 +219,7 @@ func Test_DeleteLoginRole_Is_Idempotent(t *testing.T) {
 	iamClient := New(session)
 
 	roleName := utils.GenerateRandomString(10)
-	role, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId, identityProvider, sessionDuration)
 	assert.NoError(err)
 
 	err = iamClient.DeleteLoginRole(role)
@@ -225,3 +228,32 @@ func Test_DeleteLoginRole_Is_Idempotent(t *testing.T) {
 	err = iamClient.DeleteLoginRole(role)
 	assert.NoError(err)
 }
+
+func Test_UpdateLoginRole(t *testing.T) {
+
+	assert := assert.New(t)
+
+	session := LocalStackSessionFactory{}.CreateSession()
+	iamClient := New(session)
+
+	roleName := utils.GenerateRandomString(10)
+	_, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId, identityProvider, sessionDuration)
+	assert.NoError(err)
+
+	roles, err := iamClient.ListRoles()
+	assert.NoError(err)
+	role := iamClient.lookupRole(roles, roleName)
+
+	assert.False(iamClient.LoginRoleChanged(role, accountId, identityProvider, sessionDuration))
+	assert.True(iamClient.LoginRoleChanged(role, accountId, identityProvider, time.Hour))
+	assert.True(iamClient.LoginRoleChanged(role, accountId, "Okta", sessionDuration))
+
+	err = iamClient.UpdateLoginRole(role, accountId, "Okta", time.Hour)
+	assert.NoError(err)
+
+	roles, err = iamClient.ListRoles()
+	assert.NoError(err)
+	role = iamClient.lookupRole(roles, roleName)
+
+	assert.False(iamClient.LoginRoleChanged(role, accountId, "Okta", time.Hour))
+}
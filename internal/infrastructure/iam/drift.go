@@ -0,0 +1,21 @@
+package iam
+
+import (
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+)
+
+//Compares the model with the roles in IAM without changing anything. Every difference that Apply would fix is described in the result.
+func (applier *Applier) DetectDrift(model iamCore.Model) ([]string, error) {
+
+	changes, err := applier.plan(model, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, change := range changes {
+		result = append(result, change.Description)
+	}
+	return result, nil
+}
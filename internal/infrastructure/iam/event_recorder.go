@@ -1,17 +1,27 @@
 package iam
 
+import "github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+
 type EventRecorder struct {
-	events []ApplyEventType
+	events []string
 }
 
-func (e *EventRecorder) Handle(eventType ApplyEventType, name string) {
-	e.events = append(e.events, eventType)
+//Records the changes of the report that have been made.
+func (e *EventRecorder) Record(applyReport *report.ApplyReport) {
+	if applyReport == nil {
+		return
+	}
+	for _, operation := range applyReport.Operations {
+		if operation.Outcome == report.Succeeded {
+			e.events = append(e.events, operation.Type)
+		}
+	}
 }
 
 func (e *EventRecorder) Count(eventType ApplyEventType) int {
 	result := 0
 	for _, event := range e.events {
-		if event == eventType {
+		if event == eventType.ToString() {
 			result += 1
 		}
 	}
@@ -19,7 +29,7 @@ func (e *EventRecorder) Count(eventType ApplyEventType) int {
 }
 
 func (e *EventRecorder) Reset() {
-	e.events = []ApplyEventType{}
+	e.events = []string{}
 }
 
 func (e *EventRecorder) CountAll() int {
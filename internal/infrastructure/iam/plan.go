@@ -0,0 +1,267 @@
+package iam
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/iam"
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/pmezard/go-difflib/difflib"
+	"sort"
+)
+
+// Change is a change to a role or policy that Apply would make.
+type Change struct {
+	Type        ApplyEventType
+	Name        string //the name of the role, or the name or ARN of the policy
+	Role        string //the role the policy is attached to, empty for changes to roles
+	Description string
+	Diff        string //a unified diff of the policy document, only set for the policies managed by the controller
+}
+
+//Returns true if the change deletes a role or removes a policy from a role.
+func (c Change) Destructive() bool {
+	return c.Type == RoleDeleted || c.Type == PolicyDeleted
+}
+
+//Returns a unified diff between the current and the desired policy document, an empty document means the policy doesn't exist.
+func policyDiff(name string, current string, desired string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(current),
+		B:        difflib.SplitLines(desired),
+		FromFile: fmt.Sprintf("%s (current)", name),
+		ToFile:   fmt.Sprintf("%s (desired)", name),
+		Context:  3,
+	})
+	if err != nil {
+		return "" //only writing to the string buffer can fail
+	}
+	return diff
+}
+
+//The documents of the managed policies that should be attached to the role, by policy name.
+func (applier *Applier) desiredPolicyDocuments(role *iamCore.AwsRole) (map[string]string, error) {
+	result := make(map[string]string)
+
+	for _, policy := range role.DatabaseLoginPolicies {
+		if len(policy.Databases) > 0 {
+			result[policy.DatabaseUsername] = applier.buildDatabaseLoginPolicyDocument(policy)
+		}
+	}
+
+	if len(role.Statements) > 0 {
+		document, err := applier.buildCustomPolicyDocument(role.Statements)
+		if err != nil {
+			return nil, fmt.Errorf("unable to render the policy statements of role %s: %w", role.Name, err)
+		}
+		result[applier.customPolicyName(role)] = document
+	}
+	return result, nil
+}
+
+func sortedNames(documents map[string]string) []string {
+	var result []string
+	for name := range documents {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (applier *Applier) planRoleCreation(desiredRole *iamCore.AwsRole) ([]Change, error) {
+	result := []Change{{Type: RoleCreated, Name: desiredRole.Name, Description: fmt.Sprintf("role %s does not exist", desiredRole.Name)}}
+
+	desiredDocuments, err := applier.desiredPolicyDocuments(desiredRole)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedNames(desiredDocuments) {
+		result = append(result, Change{
+			Type:        PolicyCreated,
+			Name:        name,
+			Role:        desiredRole.Name,
+			Description: fmt.Sprintf("policy %s is not attached to role %s", name, desiredRole.Name),
+			Diff:        policyDiff(name, "", desiredDocuments[name]),
+		})
+	}
+	for _, desiredPolicy := range desiredRole.Policies {
+		result = append(result, Change{
+			Type:        PolicyCreated,
+			Name:        desiredPolicy.Arn,
+			Role:        desiredRole.Name,
+			Description: fmt.Sprintf("policy %s is not attached to role %s", desiredPolicy.Arn, desiredRole.Name),
+		})
+	}
+	return result, nil
+}
+
+func (applier *Applier) planRoleUpdate(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string) ([]Change, error) {
+	var result []Change
+
+	identityProvider, sessionDuration := applier.loginSettings(desiredRole)
+	if applier.client.LoginRoleChanged(currentRole, applier.accountId, identityProvider, sessionDuration) {
+		result = append(result, Change{Type: RoleUpdated, Name: desiredRole.Name, Description: fmt.Sprintf("the identity provider or session duration of role %s differs", desiredRole.Name)})
+	}
+
+	desiredDocuments, err := applier.desiredPolicyDocuments(desiredRole)
+	if err != nil {
+		return nil, err
+	}
+
+	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(currentRole)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list attached policies: %w", err)
+	}
+
+	for _, name := range sortedNames(desiredDocuments) {
+		if applier.client.lookupAttachedPolicy(attachedPolicies, name) == nil {
+			result = append(result, Change{
+				Type:        PolicyCreated,
+				Name:        name,
+				Role:        desiredRole.Name,
+				Description: fmt.Sprintf("policy %s is not attached to role %s", name, desiredRole.Name),
+				Diff:        policyDiff(name, "", desiredDocuments[name]),
+			})
+		} else if policyDocuments[name] != desiredDocuments[name] {
+			result = append(result, Change{
+				Type:        PolicyUpdated,
+				Name:        name,
+				Role:        desiredRole.Name,
+				Description: fmt.Sprintf("policy %s attached to role %s differs", name, desiredRole.Name),
+				Diff:        policyDiff(name, policyDocuments[name], desiredDocuments[name]),
+			})
+		}
+	}
+	for _, attachedPolicy := range attachedPolicies {
+		name := *attachedPolicy.PolicyName
+		if _, ok := desiredDocuments[name]; !ok {
+			result = append(result, Change{
+				Type:        PolicyDeleted,
+				Name:        name,
+				Role:        desiredRole.Name,
+				Description: fmt.Sprintf("policy %s attached to role %s is not declared", name, desiredRole.Name),
+				Diff:        policyDiff(name, policyDocuments[name], ""),
+			})
+		}
+	}
+
+	unmanagedAttachedPolicies, err := applier.client.ListUnmanagedAttachedPolicies(currentRole)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list attached policies: %w", err)
+	}
+
+	for _, desiredPolicy := range desiredRole.Policies {
+		if applier.client.lookupAttachedPolicyByArn(unmanagedAttachedPolicies, desiredPolicy.Arn) == nil {
+			result = append(result, Change{
+				Type:        PolicyCreated,
+				Name:        desiredPolicy.Arn,
+				Role:        desiredRole.Name,
+				Description: fmt.Sprintf("policy %s is not attached to role %s", desiredPolicy.Arn, desiredRole.Name),
+			})
+		}
+	}
+	for _, attachedPolicy := range unmanagedAttachedPolicies {
+		if desiredRole.LookupReferencedPolicy(*attachedPolicy.PolicyArn) == nil {
+			result = append(result, Change{
+				Type:        PolicyDeleted,
+				Name:        *attachedPolicy.PolicyArn,
+				Role:        desiredRole.Name,
+				Description: fmt.Sprintf("policy %s attached to role %s is not declared", *attachedPolicy.PolicyArn, desiredRole.Name),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func (applier *Applier) planRoleDeletion(currentRole *iam.Role, policyDocuments map[string]string) ([]Change, error) {
+	roleName := *currentRole.RoleName
+	result := []Change{{Type: RoleDeleted, Name: roleName, Description: fmt.Sprintf("role %s is not declared", roleName)}}
+
+	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(currentRole)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list attached policies: %w", err)
+	}
+	for _, attachedPolicy := range attachedPolicies {
+		name := *attachedPolicy.PolicyName
+		result = append(result, Change{
+			Type:        PolicyDeleted,
+			Name:        name,
+			Role:        roleName,
+			Description: fmt.Sprintf("policy %s attached to role %s is not declared", name, roleName),
+			Diff:        policyDiff(name, policyDocuments[name], ""),
+		})
+	}
+
+	unmanagedAttachedPolicies, err := applier.client.ListUnmanagedAttachedPolicies(currentRole)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list attached policies: %w", err)
+	}
+	for _, attachedPolicy := range unmanagedAttachedPolicies {
+		result = append(result, Change{
+			Type:        PolicyDeleted,
+			Name:        *attachedPolicy.PolicyArn,
+			Role:        roleName,
+			Description: fmt.Sprintf("policy %s attached to role %s is not declared", *attachedPolicy.PolicyArn, roleName),
+		})
+	}
+	return result, nil
+}
+
+//Lists the changes that bring IAM in sync with the model. The policies of roles that are created or deleted are only included if withPolicies is set.
+func (applier *Applier) plan(model iamCore.Model, withPolicies bool) ([]Change, error) {
+
+	policyDocuments, err := applier.client.GetPolicyDocuments()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list policy documents: %w", err)
+	}
+
+	existingRoles, err := applier.client.ListRoles()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	var result []Change
+
+	for _, desiredRole := range model.Roles {
+		var changes []Change
+
+		existingRole := applier.lookupRole(existingRoles, desiredRole.Name)
+
+		switch {
+		case existingRole == nil && withPolicies:
+			changes, err = applier.planRoleCreation(desiredRole)
+		case existingRole == nil:
+			changes = []Change{{Type: RoleCreated, Name: desiredRole.Name, Description: fmt.Sprintf("role %s does not exist", desiredRole.Name)}}
+		default:
+			changes, err = applier.planRoleUpdate(desiredRole, existingRole, policyDocuments)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed when comparing role %s: %w", desiredRole.Name, err)
+		}
+		result = append(result, changes...)
+	}
+
+	for _, existingRole := range existingRoles {
+		if model.LookupRole(*existingRole.RoleName) != nil {
+			continue
+		}
+		if !withPolicies {
+			result = append(result, Change{Type: RoleDeleted, Name: *existingRole.RoleName, Description: fmt.Sprintf("role %s is not declared", *existingRole.RoleName)})
+			continue
+		}
+		changes, err := applier.planRoleDeletion(existingRole, policyDocuments)
+		if err != nil {
+			return nil, fmt.Errorf("failed when comparing role %s: %w", *existingRole.RoleName, err)
+		}
+		result = append(result, changes...)
+	}
+
+	return result, nil
+}
+
+//Returns the changes Apply would make to bring IAM in sync with the model, including the policies of the roles that are created or deleted, without making them.
+func (applier *Applier) Plan(model iamCore.Model) ([]Change, error) {
+	return applier.plan(model, true)
+}
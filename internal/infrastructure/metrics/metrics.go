@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+//The metrics are registered with the registry of the manager, so they are exposed on its metrics endpoint.
+var (
+	ApplyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hubble_rbac_apply_duration_seconds",
+		Help:    "The time it takes to apply the model to a managed system.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"subsystem"})
+
+	DagTasks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "hubble_rbac_redshift_dag_tasks",
+		Help: "The number of tasks in the last redshift reconciliation DAG.",
+	})
+
+	Tasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_redshift_tasks_total",
+		Help: "The number of redshift tasks that have been run, by task type and outcome.",
+	}, []string{"type", "state"})
+
+	TaskRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_redshift_task_retries_total",
+		Help: "The number of times redshift tasks have been run again after a transient error, by task type.",
+	}, []string{"type"})
+
+	IamEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_iam_events_total",
+		Help: "The number of changes made to IAM roles and policies, by event type.",
+	}, []string{"type"})
+
+	Managed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_managed_objects",
+		Help: "The number of users, roles, databases and grants managed by the controller.",
+	}, []string{"kind"})
+
+	LastSuccessfulApply = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "hubble_rbac_last_successful_apply_timestamp_seconds",
+		Help: "The time the model was last applied to all managed systems without errors.",
+	})
+
+	AuditWriteFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "hubble_rbac_audit_write_failures_total",
+		Help: "The number of audit records that could not be written to the audit sink.",
+	})
+
+	Drift = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_drift",
+		Help: "The number of differences between the spec and the managed system found by the last drift check.",
+	}, []string{"subsystem"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(ApplyDuration, DagTasks, Tasks, TaskRetries, IamEvents, Managed, LastSuccessfulApply, Drift, AuditWriteFailures)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/metrics"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
 )
 
 type RedshiftClientFactoryAdapter struct {
@@ -25,7 +27,11 @@ type Applier struct {
 	logger           logr.Logger
 }
 
+//Failed tasks are retried with IsRetryable unless the config has its own classification of errors.
 func NewApplier(clientGroup ClientGroup, excluded *redshift.Exclusions, awsAccountId string, logger logr.Logger, reconcilerConfig redshift.ReconcilerConfig) *Applier {
+	if reconcilerConfig.Retries.Retryable == nil {
+		reconcilerConfig.Retries.Retryable = IsRetryable
+	}
 	return &Applier{
 		clientGroup:      clientGroup,
 		reconcilerConfig: reconcilerConfig,
@@ -35,52 +41,114 @@ func NewApplier(clientGroup ClientGroup, excluded *redshift.Exclusions, awsAccou
 	}
 }
 
-func (applier *Applier) Apply(model redshift.Model, dryRun bool) error {
+//Builds the DAG of the tasks that bring the clusters in sync with the model.
+func (applier *Applier) buildDag(model redshift.Model, clientPool *ClientPool) (*redshift.ReconciliationDag, error) {
+
+	resolver := NewModelResolver(&RedshiftClientFactoryAdapter{clientPool: clientPool}, applier.excluded)
+
+	var clusterIdentifiers []string
+	for _, cluster := range model.Clusters {
+		clusterIdentifiers = append(clusterIdentifiers, cluster.Identifier)
+	}
+
+	applier.logger.Info("Fetching current model...")
+
+	currentModel, err := resolver.Resolve(clusterIdentifiers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	applier.logger.Info("Current model fetched")
+
+	dag := redshift.Reconcile(currentModel, &model, applier.reconcilerConfig)
+
+	applier.logger.Info("Reconciliation DAG built", "numTasks", dag.NumTasks())
+	metrics.DagTasks.Set(float64(dag.NumTasks()))
+
+	return dag, nil
+}
+
+//Returns the tasks that have been run, the report is empty in dry run mode and if the DAG could not be built.
+func (applier *Applier) Apply(model redshift.Model, dryRun bool) (*report.ApplyReport, error) {
+
+	result := report.New()
 
 	err := model.Validate(applier.excluded)
 
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	clientPool := NewClientPool(applier.clientGroup)
 
 	defer clientPool.Close()
 
-	resolver := NewModelResolver(&RedshiftClientFactoryAdapter{clientPool: clientPool}, applier.excluded)
-	var taskRunner redshift.TaskRunner
+	listener := &reportingListener{report: result}
+
+	var dagRunner redshift.DagRunner
 	if dryRun {
-		taskRunner = redshift.NewTaskPrinter(applier.logger)
+		dagRunner = redshift.NewSequentialDagRunner(redshift.NewTaskPrinter(applier.logger), nil, redshift.RetryPolicy{}, applier.logger)
+	} else if applier.reconcilerConfig.Workers > 1 {
+		dagRunner = redshift.NewParallelDagRunner(NewTaskRunnerImpl(clientPool, applier.awsAccountId, applier.logger), listener, applier.reconcilerConfig.Workers, applier.reconcilerConfig.MaxConnectionsPerCluster, applier.reconcilerConfig.Retries, applier.logger)
 	} else {
-		taskRunner = NewTaskRunnerImpl(clientPool, applier.awsAccountId, applier.logger)
+		dagRunner = redshift.NewSequentialDagRunner(NewTaskRunnerImpl(clientPool, applier.awsAccountId, applier.logger), listener, applier.reconcilerConfig.Retries, applier.logger)
 	}
 
-	dagRunner := redshift.NewSequentialDagRunner(taskRunner, applier.logger)
+	dag, err := applier.buildDag(model, clientPool)
 
-	var clusterIdentifiers []string
-	for _, cluster := range model.Clusters {
-		clusterIdentifiers = append(clusterIdentifiers, cluster.Identifier)
+	if err != nil {
+		return result, err
 	}
 
-	applier.logger.Info("Fetching current model...")
+	dagRunner.Run(dag)
 
-	currentModel, err := resolver.Resolve(clusterIdentifiers)
+	dagResult := dag.Result()
+	applier.logger.Info("Reconciliation DAG finished", "succeeded", dagResult.Succeeded, "failed", dagResult.Failed, "skipped", dagResult.Skipped, "retried", dagResult.Retried, "attempts", dagResult.Attempts)
+
+	if len(dag.GetFailed()) > 0 {
+		return result, fmt.Errorf("apply failed, %d tasks failed", len(dag.GetFailed()))
+	}
+
+	return result, nil
+}
+
+//Builds the DAG of the tasks that Apply would run, without running them.
+func (applier *Applier) plannedDag(model redshift.Model) (*redshift.ReconciliationDag, error) {
+
+	err := model.Validate(applier.excluded)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	applier.logger.Info("Current model fetched")
+	clientPool := NewClientPool(applier.clientGroup)
 
-	dag := redshift.Reconcile(currentModel, &model, applier.reconcilerConfig)
+	defer clientPool.Close()
 
-	applier.logger.Info("Reconciliation DAG built", "numTasks", dag.NumTasks())
+	return applier.buildDag(model, clientPool)
+}
 
-	dagRunner.Run(dag)
+//Returns the tasks that Apply would run to bring the clusters in sync with the model, without running them.
+func (applier *Applier) DetectDrift(model redshift.Model) ([]string, error) {
 
-	if len(dag.GetFailed()) > 0 {
-		return fmt.Errorf("apply failed, %d tasks failed", len(dag.GetFailed()))
+	dag, err := applier.plannedDag(model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Describe(), nil
+}
+
+//Returns the tasks that Apply would run along with the cluster and database they are run against.
+func (applier *Applier) Plan(model redshift.Model) ([]redshift.PlannedTask, error) {
+
+	dag, err := applier.plannedDag(model)
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return dag.Plan(), nil
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/utils"
 	"net/url"
+	"sort"
+	"strings"
 )
 
 type Client struct {
@@ -47,8 +49,8 @@ func (c *Client) Close() {
 	c.db.Close()
 }
 
-func (c *Client) bool(sql string) (bool, error) {
-	rows, err := c.db.Query(sql)
+func (c *Client) bool(sql string, args ...interface{}) (bool, error) {
+	rows, err := c.db.Query(sql, args...)
 
 	if rows != nil {
 		defer rows.Close()
@@ -69,8 +71,8 @@ func (c *Client) bool(sql string) (bool, error) {
 	return result, nil
 }
 
-func (c *Client) stringList(sql string) ([]string, error) {
-	rows, err := c.db.Query(sql)
+func (c *Client) stringList(sql string, args ...interface{}) ([]string, error) {
+	rows, err := c.db.Query(sql, args...)
 
 	if rows != nil {
 		defer rows.Close()
@@ -130,15 +132,15 @@ func (c *Client) contains(list []string, item string) bool {
 }
 
 func (c *Client) Groups() ([]string, error) {
-	return c.stringList("SELECT groname FROM pg_group WHERE groname !~ '^pg_' and groname !~'_datashare_roles'")
+	return c.stringList("SELECT groname FROM pg_group WHERE groname !~ '^pg_' and groname !~'_datashare_roles' ORDER BY groname")
 }
 
 func (c *Client) Users() ([]string, error) {
-	return c.stringList("select usename from pg_user")
+	return c.stringList("select usename from pg_user order by usename")
 }
 
 func (c *Client) Schemas() ([]string, error) {
-	return c.stringList("select nspname from pg_catalog.pg_namespace WHERE nspname !~ '^pg_' AND nspname <> 'information_schema'")
+	return c.stringList("select nspname from pg_catalog.pg_namespace WHERE nspname !~ '^pg_' AND nspname <> 'information_schema' order by nspname")
 }
 
 func (c *Client) ExternalSchemas() ([]redshift.ExternalSchema, error) {
@@ -147,7 +149,7 @@ func (c *Client) ExternalSchemas() ([]redshift.ExternalSchema, error) {
 		return []redshift.ExternalSchema{}, nil
 	}
 
-	rows, err := c.stringRows("select schemaname, databasename from svv_external_schemas")
+	rows, err := c.stringRows("select schemaname, databasename from svv_external_schemas order by schemaname")
 	if err != nil {
 		return nil, err
 	}
@@ -164,11 +166,24 @@ func (c *Client) ExternalSchemas() ([]redshift.ExternalSchema, error) {
 }
 
 func (c *Client) Databases() ([]string, error) {
-	return c.stringList("SELECT datname FROM pg_database")
+	return c.stringList("SELECT datname FROM pg_database ORDER BY datname")
 }
 
 func (c *Client) CreateDatabase(name string, owner *string) error {
 
+	database, err := NewIdentifier(name)
+	if err != nil {
+		return err
+	}
+
+	var ownerIdentifier Identifier
+	if owner != nil {
+		ownerIdentifier, err = NewIdentifier(*owner)
+		if err != nil {
+			return err
+		}
+	}
+
 	databases, err := c.Databases()
 
 	if err != nil {
@@ -177,16 +192,16 @@ func (c *Client) CreateDatabase(name string, owner *string) error {
 
 	if c.contains(databases, name) {
 		if owner != nil {
-			_, err = c.db.Exec(fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", name, *owner))
+			_, err = c.db.Exec(fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", database.Quoted(), ownerIdentifier.Quoted()))
 			return err
 		}
 		return nil
 	}
 
 	if owner != nil {
-		_, err = c.db.Exec(fmt.Sprintf("CREATE DATABASE %s WITH OWNER=%s", name, *owner))
+		_, err = c.db.Exec(fmt.Sprintf("CREATE DATABASE %s WITH OWNER=%s", database.Quoted(), ownerIdentifier.Quoted()))
 	} else {
-		_, err = c.db.Exec(fmt.Sprintf("CREATE DATABASE %s", name))
+		_, err = c.db.Exec(fmt.Sprintf("CREATE DATABASE %s", database.Quoted()))
 	}
 
 	return err
@@ -194,6 +209,11 @@ func (c *Client) CreateDatabase(name string, owner *string) error {
 
 func (c *Client) CreateGroup(groupName string) error {
 
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+
 	groups, err := c.Groups()
 
 	if err != nil {
@@ -204,13 +224,23 @@ func (c *Client) CreateGroup(groupName string) error {
 		return nil
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("CREATE GROUP %s", groupName))
+	_, err = c.db.Exec(fmt.Sprintf("CREATE GROUP %s", group.Quoted()))
 
 	return err
 }
 
 func (c *Client) DeleteGroup(groupName string) error {
 
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+
+	dummyUser, err := dummyUserOf(group)
+	if err != nil {
+		return err
+	}
+
 	groups, err := c.Groups()
 
 	if err != nil {
@@ -236,19 +266,24 @@ func (c *Client) DeleteGroup(groupName string) error {
 	}
 
 	//The dummy user might still exist if the last call to Grants ended abruptly
-	_, err = c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS dummy_%s", groupName))
+	_, err = c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s", dummyUser.Quoted()))
 
 	if err != nil {
 		return err
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("DROP GROUP %s", groupName))
+	_, err = c.db.Exec(fmt.Sprintf("DROP GROUP %s", group.Quoted()))
 
 	return err
 }
 
 func (c *Client) CreateSchema(name string) error {
 
+	schema, err := NewIdentifier(name)
+	if err != nil {
+		return err
+	}
+
 	schemas, err := c.Schemas()
 
 	if err != nil {
@@ -259,7 +294,7 @@ func (c *Client) CreateSchema(name string) error {
 		return nil
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("CREATE SCHEMA %s", name))
+	_, err = c.db.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema.Quoted()))
 	return err
 }
 
@@ -269,6 +304,17 @@ func (c *Client) CreateExternalSchema(name string, externalDatabaseName string,
 		return c.CreateSchema(name)
 	}
 
+	schema, err := NewIdentifier(name)
+	if err != nil {
+		return err
+	}
+	if err = validateGlueDatabaseName(externalDatabaseName); err != nil {
+		return err
+	}
+	if err = validateAwsAccountId(awsAccountId); err != nil {
+		return err
+	}
+
 	schemas, err := c.Schemas()
 
 	if err != nil {
@@ -282,38 +328,62 @@ func (c *Client) CreateExternalSchema(name string, externalDatabaseName string,
 	sql := `
             create external schema if not exists %s
             from data catalog
-            database '%s'
-            iam_role 'arn:aws:iam::%s:role/redshift-datalake'
+            database %s
+            iam_role %s
 `
 
-	_, err = c.db.Exec(fmt.Sprintf(sql, name, externalDatabaseName, awsAccountId))
+	role := fmt.Sprintf("arn:aws:iam::%s:role/redshift-datalake", awsAccountId)
+	_, err = c.db.Exec(fmt.Sprintf(sql, schema.Quoted(), quoteLiteral(externalDatabaseName), quoteLiteral(role)))
 	return err
 }
 
 func (c *Client) AddUserToGroup(username string, groupname string) error {
-	_, err := c.db.Exec(fmt.Sprintf("ALTER GROUP %s ADD USER %s", groupname, username))
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return err
+	}
+	group, err := NewIdentifier(groupname)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("ALTER GROUP %s ADD USER %s", group.Quoted(), user.Quoted()))
 	return err
 }
 
 func (c *Client) RemoveUserFromGroup(username string, groupname string) error {
-	_, err := c.db.Exec(fmt.Sprintf("ALTER GROUP %s DROP USER %s", groupname, username))
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return err
+	}
+	group, err := NewIdentifier(groupname)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("ALTER GROUP %s DROP USER %s", group.Quoted(), user.Quoted()))
 	return err
 }
 
 func (c *Client) PartOf(username string) ([]string, error) {
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return nil, err
+	}
+
 	sql := `
 select pg_group.groname from pg_user, pg_group  where
 pg_user.usesysid = ANY(pg_group.grolist) AND
-usename='%s'
+usename=$1
+order by pg_group.groname
 `
 
-	return c.stringList(fmt.Sprintf(sql, username))
+	return c.stringList(sql, user.folded())
 }
 
 func (c *Client) UsersAndGroups() ([]Row, error) {
 	sql := `
 select pg_user.usename, pg_group.groname from pg_user, pg_group  where
 pg_user.usesysid = ANY(pg_group.grolist)
+order by pg_user.usename, pg_group.groname
 `
 	return c.stringRows(sql)
 }
@@ -323,6 +393,7 @@ func (c *Client) Owners() ([]Row, error) {
 SELECT d.datname as "Name",
 pg_catalog.pg_get_userbyid(d.datdba) as "Owner"
 FROM pg_catalog.pg_database d
+ORDER BY d.datname
 `
 	return c.stringRows(sql)
 }
@@ -334,6 +405,11 @@ func generateRedshiftPassword() string {
 
 func (c *Client) CreateUser(username string) error {
 
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return err
+	}
+
 	users, err := c.Users()
 
 	if err != nil {
@@ -345,42 +421,81 @@ func (c *Client) CreateUser(username string) error {
 	}
 
 	//Password is set to a random string, it will never be used because we log in using IAM's GetClusterCredentials
-	_, err = c.db.Exec(fmt.Sprintf("CREATE USER %s PASSWORD '%s'", username, generateRedshiftPassword()))
+	_, err = c.db.Exec(fmt.Sprintf("CREATE USER %s PASSWORD %s", user.Quoted(), quoteLiteral(generateRedshiftPassword())))
 	return err
 }
 
 func (c *Client) DeleteUser(username string) error {
-	_, err := c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s", username))
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s", user.Quoted()))
 	return err
 }
 
-func (c *Client) SetSchemaOwner(username string, schema string) error {
-	_, err := c.db.Exec(fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", schema, username))
+func (c *Client) SetSchemaOwner(username string, schemaName string) error {
+	user, err := NewIdentifier(username)
+	if err != nil {
+		return err
+	}
+	schema, err := NewIdentifier(schemaName)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", schema.Quoted(), user.Quoted()))
 	return err
 }
 
+//The name of the dummy user that is created in the group to find the schemas granted to it.
+func dummyUserOf(group Identifier) (Identifier, error) {
+	return NewIdentifier("dummy_" + group.String())
+}
+
+//The has_schema_privilege function only works on users (not groups), therefore we need to create a dummy user in the group.
+//The returned function drops the dummy user again.
+func (c *Client) createDummyUser(group Identifier, dummyUser Identifier) (func(), error) {
+	_, err := c.db.Exec(fmt.Sprintf("CREATE USER %s PASSWORD %s IN GROUP %s", dummyUser.Quoted(), quoteLiteral(generateRedshiftPassword()), group.Quoted()))
+
+	if err != nil && !hasErrorCode(err, duplicateObjectErrorCode) {
+		return nil, err
+	}
+
+	return func() {
+		c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s", dummyUser.Quoted()))
+	}, nil
+}
+
 func (c *Client) Grants(groupName string) ([]string, error) {
 
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	dummyUser, err := dummyUserOf(group)
+	if err != nil {
+		return nil, err
+	}
+
 	schemas, err := c.Schemas()
 
 	if err != nil {
 		return nil, err
 	}
 
-	//The has_schema_privilege function only works on users (not groups), therefore we need to create a dummy user
-	_, err = c.db.Exec(fmt.Sprintf("CREATE USER dummy_%s PASSWORD '%s' IN GROUP %s", groupName, generateRedshiftPassword(), groupName))
+	dropDummyUser, err := c.createDummyUser(group, dummyUser)
 
-	if err != nil && err.(*pq.Error).Code != duplicateObjectErrorCode {
+	if err != nil {
 		return nil, err
 	}
 
-	//drop the dummy user
-	defer c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS dummy_%s", groupName))
+	defer dropDummyUser()
 
 	var result []string
 
 	for _, schema := range schemas {
-		isGranted, err := c.bool(fmt.Sprintf("select pg_catalog.has_schema_privilege('dummy_%s', '%s', 'USAGE')", groupName, schema))
+		isGranted, err := c.bool("select pg_catalog.has_schema_privilege($1, $2, 'USAGE')", dummyUser.folded(), schema)
 
 		if err != nil {
 			return nil, err
@@ -393,34 +508,379 @@ func (c *Client) Grants(groupName string) ([]string, error) {
 	return result, nil
 }
 
-func (c *Client) Grant(groupName string, schemaName string) error {
-	_, err := c.db.Exec(fmt.Sprintf("GRANT ALL ON SCHEMA %s TO GROUP %s", schemaName, groupName))
+//The privileges on the schema and on its tables that make up each privilege level.
+//The owner level adds REFERENCES on the tables to the write level, it doesn't transfer the ownership of the schema or its tables.
+type privilegeSet struct {
+	schema []string
+	tables []string
+}
+
+var privilegeSets = map[redshift.Privilege]privilegeSet{
+	redshift.ReadPrivilege:  {schema: []string{"USAGE"}, tables: []string{"SELECT"}},
+	redshift.WritePrivilege: {schema: []string{"USAGE", "CREATE"}, tables: []string{"SELECT", "INSERT", "UPDATE", "DELETE"}},
+	redshift.OwnerPrivilege: {schema: []string{"USAGE", "CREATE"}, tables: []string{"SELECT", "INSERT", "UPDATE", "DELETE", "REFERENCES"}},
+}
 
+func without(list []string, excluded []string) []string {
+	var result []string
+	for _, item := range list {
+		found := false
+		for _, x := range excluded {
+			if x == item {
+				found = true
+			}
+		}
+		if !found {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+//Grants the group exactly the given privilege on the schema.
+//Privileges of a higher level are revoked first, so the privilege level of an existing grant can be lowered.
+func (c *Client) Grant(groupName string, schemaName string, privilege redshift.Privilege) error {
+
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+	schema, err := NewIdentifier(schemaName)
 	if err != nil {
 		return err
 	}
-	_, err = c.db.Exec(fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO GROUP %s", schemaName, groupName))
 
+	granted, ok := privilegeSets[privilege.OrDefault()]
+	if !ok {
+		return fmt.Errorf("unknown privilege: %s", privilege)
+	}
+	all := privilegeSets[redshift.OwnerPrivilege]
+
+	var statements []string
+
+	if excess := without(all.schema, granted.schema); len(excess) > 0 {
+		statements = append(statements, fmt.Sprintf("REVOKE %s ON SCHEMA %s FROM GROUP %s", strings.Join(excess, ", "), schema.Quoted(), group.Quoted()))
+	}
+	if excess := without(all.tables, granted.tables); len(excess) > 0 {
+		statements = append(statements,
+			fmt.Sprintf("REVOKE %s ON ALL TABLES IN SCHEMA %s FROM GROUP %s", strings.Join(excess, ", "), schema.Quoted(), group.Quoted()),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE %s ON TABLES FROM GROUP %s", schema.Quoted(), strings.Join(excess, ", "), group.Quoted()))
+	}
+
+	statements = append(statements,
+		fmt.Sprintf("GRANT %s ON SCHEMA %s TO GROUP %s", strings.Join(granted.schema, ", "), schema.Quoted(), group.Quoted()),
+		fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO GROUP %s", strings.Join(granted.tables, ", "), schema.Quoted(), group.Quoted()),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT %s ON TABLES TO GROUP %s", schema.Quoted(), strings.Join(granted.tables, ", "), group.Quoted()))
+
+	for _, statement := range statements {
+		_, err := c.db.Exec(statement)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Returns the privileges on tables created in the given schema that are granted to the given group by default, using the
+//abbreviations of the privileges from the ACL (e.g. "arwdx"). It is empty if the group has no default privileges in the schema.
+func (c *Client) defaultTablePrivileges(groupName string, schemaName string) (string, error) {
+	sql := `
+select array_to_string(d.defaclacl, ',') from pg_default_acl d, pg_namespace n
+where d.defaclnamespace = n.oid and d.defaclobjtype = 'r' and n.nspname = $1
+`
+	acls, err := c.stringList(sql, schemaName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, acl := range acls {
+		if privileges := aclPrivileges(acl, groupName); privileges != "" {
+			return privileges, nil
+		}
+	}
+	return "", nil
+}
+
+//Returns the abbreviations of the privileges granted to the group in an ACL (e.g. "arwdx"), or an empty string if none are granted.
+func aclPrivileges(acl string, groupName string) string {
+	for _, item := range strings.Split(acl, ",") {
+		//items have the form "group <name>=<privileges>/<grantor>", redshift prefixes groups with "group "
+		item = strings.TrimPrefix(strings.TrimSpace(item), "group ")
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] != groupName {
+			continue
+		}
+		return strings.SplitN(parts[1], "/", 2)[0]
+	}
+	return ""
+}
+
+//Returns the privilege level granted to the group on each of the schemas the group has access to.
+//Schemas that the group can only use to access the given tables, as returned by TableGrants, are not included.
+//The level is derived from the default privileges of the group on tables in the schema, as those are only granted to the group itself.
+//The privileges of the group on the schema are only used to recognize grants made before privilege levels were introduced.
+func (c *Client) Privileges(groupName string, tables []redshift.Table) (map[string]redshift.Privilege, error) {
+
+	grants, err := c.Grants(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]redshift.Privilege)
+
+	for _, schema := range grants {
+		defaults, err := c.defaultTablePrivileges(groupName, schema)
+		if err != nil {
+			return nil, err
+		}
+		//the group is only granted usage on the schema to access the tables granted to it
+		if defaults == "" && containsTableIn(tables, schema) {
+			continue
+		}
+		schemaPrivileges, err := c.schemaPrivileges(groupName, schema)
+		if err != nil {
+			return nil, err
+		}
+		result[schema] = privilegeLevel(defaults, schemaPrivileges)
+	}
+
+	return result, nil
+}
+
+//Returns the privileges granted to the group itself on the schema, using the abbreviations of the privileges from the ACL (e.g. "UC").
+//Privileges granted to everyone, such as CREATE on the public schema, are not included.
+func (c *Client) schemaPrivileges(groupName string, schemaName string) (string, error) {
+	acls, err := c.stringList("select array_to_string(nspacl, ',') from pg_namespace where nspname = $1 and nspacl is not null", schemaName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, acl := range acls {
+		if privileges := aclPrivileges(acl, groupName); privileges != "" {
+			return privileges, nil
+		}
+	}
+	return "", nil
+}
+
+//Works out the privilege level from the privileges of the group on tables created in the schema and on the schema itself.
+//INSERT (a) is part of the write level and REFERENCES (x) is only part of the owner level.
+//Earlier versions granted ALL on the schema with SELECT on its tables, which is CREATE (C) without INSERT.
+func privilegeLevel(defaults string, schemaPrivileges string) redshift.Privilege {
+	switch {
+	case strings.Contains(defaults, "x"):
+		return redshift.OwnerPrivilege
+	case strings.Contains(defaults, "a"):
+		return redshift.WritePrivilege
+	case strings.Contains(schemaPrivileges, "C"):
+		return redshift.LegacyPrivilege
+	default:
+		return redshift.ReadPrivilege
+	}
+}
+
+func (c *Client) Revoke(groupName string, schemaName string) error {
+
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+	schema, err := NewIdentifier(schemaName)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("REVOKE ALL ON ALL TABLES IN SCHEMA %s FROM GROUP %s", schema.Quoted(), group.Quoted()))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE ALL ON TABLES FROM GROUP %s", schema.Quoted(), group.Quoted()))
 	if err != nil {
 		return err
 	}
-	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT SELECT ON TABLES TO GROUP %s", schemaName, groupName))
 
+	_, err = c.db.Exec(fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM GROUP %s", schema.Quoted(), group.Quoted()))
 	return err
 }
 
-func (c *Client) Revoke(groupName string, schemaName string) error {
+func containsTableIn(tables []redshift.Table, schemaName string) bool {
+	for _, table := range tables {
+		if table.Schema == schemaName {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns the tables that the group is granted SELECT on, with the columns if only some columns are granted.
+//Tables in schemas that the group has been granted access to as a whole (and therefore has default privileges in) are not included.
+func (c *Client) TableGrants(groupName string) ([]redshift.Table, error) {
+
+	defaultAcls, err := c.stringRows(`
+select n.nspname, array_to_string(d.defaclacl, ',') from pg_default_acl d, pg_namespace n
+where d.defaclnamespace = n.oid and d.defaclobjtype = 'r'
+`)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaGrants := make(map[string]bool)
+	for _, row := range defaultAcls {
+		if aclPrivileges(row.Cells[1], groupName) != "" {
+			schemaGrants[row.Cells[0]] = true
+		}
+	}
+
+	tableAcls, err := c.stringRows(`
+select n.nspname || '.' || c.relname, array_to_string(c.relacl, ',') from pg_class c, pg_namespace n
+where c.relnamespace = n.oid and c.relkind in ('r', 'v') and c.relacl is not null
+and n.nspname !~ '^pg_' and n.nspname <> 'information_schema'
+`)
+	if err != nil {
+		return nil, err
+	}
+
+	//redshift only exposes the column privileges in pg_attribute_info, the postgres database used for testing has them in pg_attribute
+	attributes := "pg_attribute"
+	if c.externalSchemasSupported {
+		attributes = "pg_attribute_info"
+	}
+
+	columnAcls, err := c.stringRows(fmt.Sprintf(`
+select n.nspname || '.' || c.relname || '.' || a.attname, array_to_string(a.attacl, ',') from %s a, pg_class c, pg_namespace n
+where a.attrelid = c.oid and c.relnamespace = n.oid and a.attacl is not null
+and n.nspname !~ '^pg_' and n.nspname <> 'information_schema'
+`, attributes))
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*redshift.Table)
+
+	for _, row := range tableAcls {
+		parts := strings.SplitN(row.Cells[0], ".", 2)
+		if schemaGrants[parts[0]] || !strings.Contains(aclPrivileges(row.Cells[1], groupName), "r") {
+			continue
+		}
+		tables[row.Cells[0]] = &redshift.Table{Schema: parts[0], Name: parts[1]}
+	}
+
+	for _, row := range columnAcls {
+		parts := strings.SplitN(row.Cells[0], ".", 3)
+		if schemaGrants[parts[0]] || !strings.Contains(aclPrivileges(row.Cells[1], groupName), "r") {
+			continue
+		}
+		identifier := fmt.Sprintf("%s.%s", parts[0], parts[1])
+		table, ok := tables[identifier]
+		if !ok {
+			table = &redshift.Table{Schema: parts[0], Name: parts[1], Columns: []string{}}
+			tables[identifier] = table
+		}
+		//a table that is granted as a whole has no columns
+		if table.Columns != nil {
+			table.Columns = append(table.Columns, parts[2])
+		}
+	}
+
+	var identifiers []string
+	for identifier := range tables {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	var result []redshift.Table
+	for _, identifier := range identifiers {
+		table := tables[identifier]
+		sort.Strings(table.Columns)
+		result = append(result, *table)
+	}
+	return result, nil
+}
+
+func tablePrivilegeTarget(table redshift.Table) (string, error) {
+	schema, err := NewIdentifier(table.Schema)
+	if err != nil {
+		return "", err
+	}
+	name, err := NewIdentifier(table.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(table.Columns) == 0 {
+		return fmt.Sprintf("SELECT ON %s", qualified(schema, name)), nil
+	}
+
+	var columns []string
+	for _, column := range table.Columns {
+		identifier, err := NewIdentifier(column)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, identifier.Quoted())
+	}
+	return fmt.Sprintf("SELECT (%s) ON %s", strings.Join(columns, ", "), qualified(schema, name)), nil
+}
+
+//Grants the group SELECT on the table, or on the given columns of the table. The group is granted usage on the schema to be able to access the table.
+func (c *Client) GrantTable(groupName string, table redshift.Table) error {
+
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+	target, err := tablePrivilegeTarget(table)
+	if err != nil {
+		return err
+	}
+	schema, err := NewIdentifier(table.Schema)
+	if err != nil {
+		return err
+	}
 
-	_, err := c.db.Exec(fmt.Sprintf("REVOKE SELECT ON ALL TABLES IN SCHEMA %s FROM GROUP %s", schemaName, groupName))
+	_, err = c.db.Exec(fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO GROUP %s", schema.Quoted(), group.Quoted()))
 	if err != nil {
 		return err
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE SELECT ON TABLES FROM GROUP %s", schemaName, groupName))
+	_, err = c.db.Exec(fmt.Sprintf("GRANT %s TO GROUP %s", target, group.Quoted()))
+	return err
+}
+
+//Revokes SELECT on the table, or on the given columns of the table, from the group.
+//Usage on the schema is revoked as well if the group is no longer granted any tables in it and has not been granted access to the schema as a whole.
+func (c *Client) RevokeTable(groupName string, table redshift.Table) error {
+
+	group, err := NewIdentifier(groupName)
+	if err != nil {
+		return err
+	}
+	target, err := tablePrivilegeTarget(table)
+	if err != nil {
+		return err
+	}
+	schema, err := NewIdentifier(table.Schema)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("REVOKE %s FROM GROUP %s", target, group.Quoted()))
 	if err != nil {
 		return err
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM GROUP %s", schemaName, groupName))
+	defaults, err := c.defaultTablePrivileges(groupName, table.Schema)
+	if err != nil {
+		return err
+	}
+
+	remaining, err := c.TableGrants(groupName)
+	if err != nil {
+		return err
+	}
+
+	if defaults == "" && !containsTableIn(remaining, table.Schema) {
+		_, err = c.db.Exec(fmt.Sprintf("REVOKE USAGE ON SCHEMA %s FROM GROUP %s", schema.Quoted(), group.Quoted()))
+	}
 	return err
 }
@@ -1,9 +1,13 @@
 package redshift
 
+import "sync"
+
+//ClientPool keeps one client per database. It is safe for concurrent use, the clients share their connections between the tasks run against the same database.
 type ClientPool struct {
 	clientGroup   ClientGroup
 	masterClients map[string]*Client
 	clients       map[string]*Client
+	lock          sync.Mutex
 }
 
 func NewClientPool(clientGroup ClientGroup) *ClientPool {
@@ -15,6 +19,8 @@ func NewClientPool(clientGroup ClientGroup) *ClientPool {
 }
 
 func (c *ClientPool) GetClusterClient(clusterIdentifier string) (*Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
 	client, ok := c.masterClients[clusterIdentifier]
 
@@ -31,6 +37,8 @@ func (c *ClientPool) GetClusterClient(clusterIdentifier string) (*Client, error)
 }
 
 func (c *ClientPool) GetDatabaseClient(clusterIdentifier string, databaseName string) (*Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
 	identifier := clusterIdentifier + "." + databaseName
 	client, ok := c.clients[identifier]
@@ -48,6 +56,9 @@ func (c *ClientPool) GetDatabaseClient(clusterIdentifier string, databaseName st
 }
 
 func (c *ClientPool) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	for _, client := range c.clients {
 		client.Close()
 	}
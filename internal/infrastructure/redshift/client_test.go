@@ -5,6 +5,7 @@ package redshift
 //YOU MUST RUN docker-compose up PRIOR TO RUNNING THIS TEST
 
 import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/utils"
 	"github.com/stretchr/testify/assert"
 	"strings"
@@ -38,13 +39,31 @@ func TestClient_CreateUser(t *testing.T) {
 	assert.NoError(err)
 	assert.Contains(groups, groupName)
 
-	err = client.Grant(groupName, schema)
+	err = client.Grant(groupName, schema, redshift.WritePrivilege)
 	assert.NoError(err)
 
 	grants, err := client.Grants(groupName)
 	assert.NoError(err)
 	assert.Contains(grants, schema)
 
+	privileges, err := client.Privileges(groupName, nil)
+	assert.NoError(err)
+	assert.Equal(redshift.WritePrivilege, privileges[schema])
+
+	err = client.Grant(groupName, schema, redshift.ReadPrivilege)
+	assert.NoError(err)
+
+	privileges, err = client.Privileges(groupName, nil)
+	assert.NoError(err)
+	assert.Equal(redshift.ReadPrivilege, privileges[schema], "the privilege level can be lowered")
+
+	err = client.Grant(groupName, schema, redshift.OwnerPrivilege)
+	assert.NoError(err)
+
+	privileges, err = client.Privileges(groupName, nil)
+	assert.NoError(err)
+	assert.Equal(redshift.OwnerPrivilege, privileges[schema])
+
 	err = client.Revoke(groupName, schema)
 	assert.NoError(err)
 
@@ -57,3 +76,93 @@ func TestClient_CreateUser(t *testing.T) {
 	err = client.DeleteUser(username)
 	assert.NoError(err)
 }
+
+func TestClient_LegacyGrant(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schema := "public"
+	groupName := "clienttest_legacy"
+
+	client, _ := NewClient("lunarway", "lunarway", "localhost", "lunarway", "disable", 5432, false)
+
+	err := client.CreateGroup(groupName)
+	assert.NoError(err)
+
+	//the statements run by earlier versions of the controller
+	_, err = client.db.Exec("GRANT ALL ON SCHEMA public TO GROUP clienttest_legacy")
+	assert.NoError(err)
+	_, err = client.db.Exec("GRANT SELECT ON ALL TABLES IN SCHEMA public TO GROUP clienttest_legacy")
+	assert.NoError(err)
+	_, err = client.db.Exec("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT ON TABLES TO GROUP clienttest_legacy")
+	assert.NoError(err)
+
+	privileges, err := client.Privileges(groupName, nil)
+	assert.NoError(err)
+	assert.Equal(redshift.LegacyPrivilege, privileges[schema], "the legacy grant is not mistaken for a read grant")
+
+	err = client.Grant(groupName, schema, redshift.ReadPrivilege)
+	assert.NoError(err)
+
+	privileges, err = client.Privileges(groupName, nil)
+	assert.NoError(err)
+	assert.Equal(redshift.ReadPrivilege, privileges[schema], "CREATE is revoked when the grant is lowered to read")
+
+	err = client.DeleteGroup(groupName)
+	assert.NoError(err)
+}
+
+func TestClient_TableGrants(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schema := "clienttest_pii"
+	groupName := "clienttest_tables"
+
+	client, _ := NewClient("lunarway", "lunarway", "localhost", "lunarway", "disable", 5432, false)
+
+	err := client.CreateGroup(groupName)
+	assert.NoError(err)
+
+	err = client.CreateSchema(schema)
+	assert.NoError(err)
+
+	_, err = client.db.Exec("CREATE TABLE IF NOT EXISTS clienttest_pii.customers (id int, name text, ssn text)")
+	assert.NoError(err)
+	_, err = client.db.Exec("CREATE TABLE IF NOT EXISTS clienttest_pii.countries (id int, name text)")
+	assert.NoError(err)
+
+	err = client.GrantTable(groupName, redshift.Table{Schema: schema, Name: "countries"})
+	assert.NoError(err)
+
+	err = client.GrantTable(groupName, redshift.Table{Schema: schema, Name: "customers", Columns: []string{"id", "name"}})
+	assert.NoError(err)
+
+	tables, err := client.TableGrants(groupName)
+	assert.NoError(err)
+	assert.Equal([]redshift.Table{
+		{Schema: schema, Name: "countries"},
+		{Schema: schema, Name: "customers", Columns: []string{"id", "name"}},
+	}, tables)
+
+	privileges, err := client.Privileges(groupName, tables)
+	assert.NoError(err)
+	assert.NotContains(privileges, schema, "usage on the schema is only granted to access the tables")
+
+	err = client.RevokeTable(groupName, redshift.Table{Schema: schema, Name: "countries"})
+	assert.NoError(err)
+
+	err = client.RevokeTable(groupName, redshift.Table{Schema: schema, Name: "customers", Columns: []string{"id", "name"}})
+	assert.NoError(err)
+
+	tables, err = client.TableGrants(groupName)
+	assert.NoError(err)
+	assert.Empty(tables)
+
+	grants, err := client.Grants(groupName)
+	assert.NoError(err)
+	assert.NotContains(grants, schema, "usage on the schema is revoked with the last table")
+
+	err = client.DeleteGroup(groupName)
+	assert.NoError(err)
+}
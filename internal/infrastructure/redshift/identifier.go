@@ -0,0 +1,75 @@
+package redshift
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//Redshift truncates longer names, so two names that only differ after the limit would refer to the same object.
+const maxIdentifierLength = 127
+
+//The names redshift accepts without quoting: ASCII letters, digits, underscores and dollar signs, not starting with a digit or a dollar sign.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+//Glue database names are put into a string literal rather than used as an identifier, glue allows hyphens in them.
+var glueDatabasePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,255}$`)
+
+var awsAccountIdPattern = regexp.MustCompile(`^[0-9]{12}$`)
+
+//Identifier is the name of a user, group, database, schema, table or column that has been validated, so it can be put into a SQL statement.
+type Identifier struct {
+	name string
+}
+
+func NewIdentifier(name string) (Identifier, error) {
+	if len(name) > maxIdentifierLength {
+		return Identifier{}, fmt.Errorf("invalid identifier %q: it is longer than %d characters", name, maxIdentifierLength)
+	}
+	if !identifierPattern.MatchString(name) {
+		return Identifier{}, fmt.Errorf("invalid identifier %q: only letters, digits, underscores and dollar signs are allowed, and it must start with a letter or an underscore", name)
+	}
+	return Identifier{name: name}, nil
+}
+
+//Redshift folds names to lower case unless they are quoted, so the name is folded before it is quoted to refer to the same object as the unquoted name.
+func (i Identifier) folded() string {
+	return strings.ToLower(i.name)
+}
+
+//Returns the name as a quoted identifier. Quoting keeps reserved words such as user from being parsed as keywords.
+func (i Identifier) Quoted() string {
+	return `"` + strings.ReplaceAll(i.folded(), `"`, `""`) + `"`
+}
+
+func (i Identifier) String() string {
+	return i.name
+}
+
+//Returns the quoted name of a table, or of a column when given the table and the column.
+func qualified(identifiers ...Identifier) string {
+	var quoted []string
+	for _, identifier := range identifiers {
+		quoted = append(quoted, identifier.Quoted())
+	}
+	return strings.Join(quoted, ".")
+}
+
+//Returns the value as a string literal. The values are validated or generated by the controller, the quotes are doubled regardless.
+func quoteLiteral(value string) string {
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
+
+func validateGlueDatabaseName(name string) error {
+	if !glueDatabasePattern.MatchString(name) {
+		return fmt.Errorf("invalid glue database name %q: only letters, digits, underscores and hyphens are allowed", name)
+	}
+	return nil
+}
+
+func validateAwsAccountId(accountId string) error {
+	if !awsAccountIdPattern.MatchString(accountId) {
+		return fmt.Errorf("invalid AWS account id %q: it must be 12 digits", accountId)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package redshift
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/stretchr/testify/assert"
+)
+
+var hostileNames = []string{
+	"",
+	"x; DROP TABLE y",
+	"x;--",
+	"--",
+	`a"b`,
+	"a'b",
+	"a b",
+	"a.b",
+	"1abc",
+	"$abc",
+	"a\x00b",
+	"a\nb",
+	"ænder",
+	strings.Repeat("a", maxIdentifierLength+1),
+}
+
+func Test_NewIdentifier(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, name := range []string{"analyst", "jwr_bianalyst", "_private", "Sales2020", "a$b", strings.Repeat("a", maxIdentifierLength)} {
+		identifier, err := NewIdentifier(name)
+		assert.NoError(err, name)
+		assert.Equal(name, identifier.String())
+	}
+
+	for _, name := range hostileNames {
+		_, err := NewIdentifier(name)
+		assert.Error(err, "%q is rejected", name)
+	}
+}
+
+func Test_Identifier_Quoted(t *testing.T) {
+
+	assert := assert.New(t)
+
+	user, _ := NewIdentifier("user")
+	assert.Equal(`"user"`, user.Quoted(), "reserved words are quoted")
+
+	mixedCase, _ := NewIdentifier("BiAnalyst")
+	assert.Equal(`"bianalyst"`, mixedCase.Quoted(), "names are folded to lower case like redshift does for unquoted names")
+
+	schema, _ := NewIdentifier("public")
+	table, _ := NewIdentifier("Orders")
+	column, _ := NewIdentifier("id")
+	assert.Equal(`"public"."orders"`, qualified(schema, table))
+	assert.Equal(`"public"."orders"."id"`, qualified(schema, table, column))
+}
+
+func Test_QuoteLiteral(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal(`'lake'`, quoteLiteral("lake"))
+	assert.Equal(`'it''s'`, quoteLiteral("it's"))
+	assert.Equal(`''');DROP TABLE x;--'`, quoteLiteral("');DROP TABLE x;--"))
+}
+
+func Test_ValidateExternalSchemaArguments(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.NoError(validateGlueDatabaseName("lake"))
+	assert.NoError(validateGlueDatabaseName("data-lake_2"))
+	assert.Error(validateGlueDatabaseName(""))
+	assert.Error(validateGlueDatabaseName("lake' iam_role 'arn:aws:iam::000000000000:role/admin"))
+	assert.Error(validateGlueDatabaseName(strings.Repeat("a", 256)))
+
+	assert.NoError(validateAwsAccountId("123456789012"))
+	assert.Error(validateAwsAccountId("12345678901"))
+	assert.Error(validateAwsAccountId("123456789012:role/admin"))
+	assert.Error(validateAwsAccountId(""))
+}
+
+func Test_TablePrivilegeTarget(t *testing.T) {
+
+	assert := assert.New(t)
+
+	target, err := tablePrivilegeTarget(redshift.Table{Schema: "public", Name: "orders"})
+	assert.NoError(err)
+	assert.Equal(`SELECT ON "public"."orders"`, target)
+
+	target, err = tablePrivilegeTarget(redshift.Table{Schema: "public", Name: "orders", Columns: []string{"id", "amount"}})
+	assert.NoError(err)
+	assert.Equal(`SELECT ("id", "amount") ON "public"."orders"`, target)
+
+	_, err = tablePrivilegeTarget(redshift.Table{Schema: "public", Name: "orders; DROP TABLE users"})
+	assert.Error(err)
+
+	_, err = tablePrivilegeTarget(redshift.Table{Schema: "public", Name: "orders", Columns: []string{"id) ON users TO GROUP public; --"}})
+	assert.Error(err, "hostile column names are rejected")
+}
+
+//The names are validated before the client talks to the database, so a client without a connection is enough.
+func Test_Client_RejectsHostileNames(t *testing.T) {
+
+	assert := assert.New(t)
+
+	client := &Client{}
+
+	for _, name := range hostileNames {
+		assert.Error(client.CreateUser(name), "CreateUser(%q)", name)
+		assert.Error(client.DeleteUser(name), "DeleteUser(%q)", name)
+		assert.Error(client.CreateGroup(name), "CreateGroup(%q)", name)
+		assert.Error(client.DeleteGroup(name), "DeleteGroup(%q)", name)
+		assert.Error(client.CreateSchema(name), "CreateSchema(%q)", name)
+		assert.Error(client.CreateDatabase(name, nil), "CreateDatabase(%q)", name)
+		assert.Error(client.CreateDatabase("analyst", &name), "CreateDatabase with owner %q", name)
+		assert.Error(client.AddUserToGroup(name, "analyst"), "AddUserToGroup(%q)", name)
+		assert.Error(client.AddUserToGroup("jwr", name), "AddUserToGroup to %q", name)
+		assert.Error(client.RemoveUserFromGroup(name, "analyst"), "RemoveUserFromGroup(%q)", name)
+		assert.Error(client.SetSchemaOwner(name, "public"), "SetSchemaOwner(%q)", name)
+		assert.Error(client.SetSchemaOwner("jwr", name), "SetSchemaOwner of %q", name)
+		assert.Error(client.Grant(name, "public", redshift.ReadPrivilege), "Grant(%q)", name)
+		assert.Error(client.Grant("analyst", name, redshift.ReadPrivilege), "Grant on %q", name)
+		assert.Error(client.Revoke(name, "public"), "Revoke(%q)", name)
+		assert.Error(client.GrantTable(name, redshift.Table{Schema: "public", Name: "orders"}), "GrantTable(%q)", name)
+		assert.Error(client.RevokeTable("analyst", redshift.Table{Schema: name, Name: "orders"}), "RevokeTable in %q", name)
+
+		_, err := client.PartOf(name)
+		assert.Error(err, "PartOf(%q)", name)
+		_, err = client.Grants(name)
+		assert.Error(err, "Grants(%q)", name)
+	}
+
+	client.externalSchemasSupported = true
+	assert.Error(client.CreateExternalSchema("lake", "lake'; DROP SCHEMA public; --", "123456789012"))
+	assert.Error(client.CreateExternalSchema("lake", "lake", "123456789012:role/admin"))
+	assert.Error(client.CreateExternalSchema("lake; --", "lake", "123456789012"))
+}
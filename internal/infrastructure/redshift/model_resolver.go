@@ -3,6 +3,7 @@ package redshift
 import (
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"golang.org/x/sync/errgroup"
+	"sort"
 )
 
 // The ModelResolver can query the clusters and resolve the current state and return it as a redshift.Model.
@@ -22,7 +23,8 @@ type RedshiftClient interface {
 	Groups() ([]string, error)
 	Databases() ([]string, error)
 	ExternalSchemas() ([]redshift.ExternalSchema, error)
-	Grants(groupName string) ([]string, error)
+	Privileges(groupName string, tables []redshift.Table) (map[string]redshift.Privilege, error)
+	TableGrants(groupName string) ([]redshift.Table, error)
 }
 
 func NewModelResolver(redshiftClientFactory RedshiftClientFactory, excluded *redshift.Exclusions) *ModelResolver {
@@ -112,11 +114,22 @@ func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshi
 		for _, group := range groups {
 			databaseGroup := database.DeclareGroup(group)
 
-			grants, err := databaseClient.Grants(group)
+			tables, err := databaseClient.TableGrants(group)
 			if err != nil {
 				return err
 			}
 
+			privileges, err := databaseClient.Privileges(group, tables)
+			if err != nil {
+				return err
+			}
+
+			var grants []string
+			for schema := range privileges {
+				grants = append(grants, schema)
+			}
+			sort.Strings(grants)
+
 			for _, schema := range grants {
 
 				externalSchema, ok := lookupExternalSchema(schema, externalSchemas)
@@ -124,9 +137,13 @@ func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshi
 				if ok {
 					databaseGroup.GrantExternalSchema(&externalSchema)
 				} else {
-					databaseGroup.GrantSchema(&redshift.Schema{Name: schema})
+					databaseGroup.GrantSchema(&redshift.Schema{Name: schema, Privilege: privileges[schema]})
 				}
 			}
+
+			for i := range tables {
+				databaseGroup.GrantTable(&tables[i])
+			}
 		}
 	}
 	return nil
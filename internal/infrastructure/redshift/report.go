@@ -0,0 +1,39 @@
+package redshift
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
+
+//Adds every task that has finished to the report.
+type reportingListener struct {
+	report *report.ApplyReport
+}
+
+func outcome(state redshift.TaskState) report.Outcome {
+	switch state {
+	case redshift.Success:
+		return report.Succeeded
+	case redshift.Skipped:
+		return report.Skipped
+	default:
+		return report.Failed
+	}
+}
+
+func (l *reportingListener) TaskFinished(task *redshift.Task, err error) {
+	cluster, database := task.Location()
+
+	l.report.Add(report.Operation{
+		System:   report.Redshift,
+		Type:     task.Type().String(),
+		Target:   task.Identifier(),
+		Cluster:  cluster,
+		Database: database,
+		Detail:   task.Detail(),
+		Outcome:  outcome(task.State()),
+		Err:      err,
+		Duration: task.Duration(),
+		Attempts: task.Attempts(),
+	})
+}
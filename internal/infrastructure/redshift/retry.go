@@ -0,0 +1,65 @@
+package redshift
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/lib/pq"
+)
+
+//The classes of postgres error codes that are caused by the state of the cluster rather than the statement, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+var retryableErrorClasses = []pq.ErrorClass{
+	"08", //connection exception
+	"40", //transaction rollback, e.g. serialization failures and deadlocks
+	"53", //insufficient resources
+}
+
+var retryableErrorCodes = []pq.ErrorCode{
+	"55P03", //lock_not_available
+	"57P01", //admin_shutdown
+	"57P02", //crash_shutdown
+	"57P03", //cannot_connect_now
+}
+
+//Returns true if the error has the given postgres error code.
+func hasErrorCode(err error, code pq.ErrorCode) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == code
+}
+
+//IsRetryable returns true if the error is likely to be transient, so running the statement again may succeed.
+//Errors caused by the statement itself, e.g. syntax errors or missing privileges, are permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		for _, class := range retryableErrorClasses {
+			if pqErr.Code.Class() == class {
+				return true
+			}
+		}
+		for _, code := range retryableErrorCodes {
+			if pqErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
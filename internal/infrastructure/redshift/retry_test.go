@@ -0,0 +1,38 @@
+package redshift
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsRetryable(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.False(IsRetryable(nil))
+
+	assert.True(IsRetryable(&pq.Error{Code: "08006"}), "connection failures are retryable")
+	assert.True(IsRetryable(&pq.Error{Code: "40001"}), "serialization failures are retryable")
+	assert.True(IsRetryable(&pq.Error{Code: "53300"}), "too many connections is retryable")
+	assert.True(IsRetryable(&pq.Error{Code: "55P03"}), "lock timeouts are retryable")
+	assert.True(IsRetryable(&pq.Error{Code: "57P01"}), "an admin shutdown is retryable")
+	assert.True(IsRetryable(fmt.Errorf("unable to create user x in dev: %w", &pq.Error{Code: "40P01"})), "wrapped errors are classified")
+
+	assert.False(IsRetryable(&pq.Error{Code: "42601"}), "syntax errors are permanent")
+	assert.False(IsRetryable(&pq.Error{Code: "42501"}), "missing privileges are permanent")
+	assert.False(IsRetryable(&pq.Error{Code: "42710"}), "duplicate objects are permanent")
+	assert.False(IsRetryable(&pq.Error{Code: "55006"}), "objects in use are permanent")
+
+	assert.True(IsRetryable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), "network errors are retryable")
+	assert.True(IsRetryable(fmt.Errorf("query failed: %w", driver.ErrBadConn)))
+	assert.True(IsRetryable(io.ErrUnexpectedEOF))
+
+	assert.False(IsRetryable(errors.New("unknown cluster")), "other errors are permanent")
+}
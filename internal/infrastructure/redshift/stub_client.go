@@ -60,6 +60,14 @@ func (c *StubRedshiftClient) Databases() ([]string, error) {
 func (c *StubRedshiftClient) ExternalSchemas() ([]redshift.ExternalSchema, error) {
 	return c.externalSchemas, nil
 }
-func (c *StubRedshiftClient) Grants(groupName string) ([]string, error) {
-	return c.grants, nil
+func (c *StubRedshiftClient) Privileges(groupName string, tables []redshift.Table) (map[string]redshift.Privilege, error) {
+	result := make(map[string]redshift.Privilege)
+	for _, schema := range c.grants {
+		result[schema] = redshift.ReadPrivilege
+	}
+	return result, nil
+}
+
+func (c *StubRedshiftClient) TableGrants(groupName string) ([]redshift.Table, error) {
+	return nil, nil
 }
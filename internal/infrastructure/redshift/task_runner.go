@@ -3,7 +3,6 @@ package redshift
 import (
 	"fmt"
 	"github.com/go-logr/logr"
-	"github.com/lib/pq"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/prometheus/common/log"
 )
@@ -29,7 +28,7 @@ func (t *TaskRunnerImpl) CreateUser(model *redshift.UserModel) error {
 	err = client.CreateUser(model.User.Name)
 
 	if err != nil {
-		return fmt.Errorf("unable to create user %s in %s: %w", model.User.Name, "cluster.Identifier", err)
+		return fmt.Errorf("unable to create user %s in %s: %w", model.User.Name, model.ClusterIdentifier, err)
 	}
 	return nil
 }
@@ -45,7 +44,7 @@ func (t *TaskRunnerImpl) DropUser(model *redshift.UserModel) error {
 	err = client.DeleteUser(model.User.Name)
 
 	if err != nil {
-		if err.(*pq.Error).Code == objectInUse {
+		if hasErrorCode(err, objectInUse) {
 			log.Warnf("unable to delete user %s in cluster %s because it in use. This will happen if the user is a DbtDeveloper role because it owns a database. You'll need to delete manually", model.User.Name, model.ClusterIdentifier)
 		} else {
 			return fmt.Errorf("unable to delete user %s in %s: %w", model.User.Name, model.ClusterIdentifier, err)
@@ -148,17 +147,17 @@ func (t *TaskRunnerImpl) CreateDatabase(model *redshift.DatabaseModel) error {
 }
 
 func (t *TaskRunnerImpl) GrantAccess(model *redshift.GrantsModel) error {
-	t.log.Info(fmt.Sprintf("GrantAccess (%s.%s) %s->%s", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.SchemaName))
+	t.log.Info(fmt.Sprintf("GrantAccess (%s.%s) %s->%s (%s)", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.SchemaName, model.Privilege))
 
 	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
 
 	if err != nil {
 		return err
 	}
-	err = client.Grant(model.GroupName, model.SchemaName)
+	err = client.Grant(model.GroupName, model.SchemaName, model.Privilege)
 
 	if err != nil {
-		return fmt.Errorf("failed to grant acccess to schema %s for group %s on database %s: %w", model.SchemaName, model.GroupName, model.Database.Identifier(), err)
+		return fmt.Errorf("failed to grant %s acccess to schema %s for group %s on database %s: %w", model.Privilege, model.SchemaName, model.GroupName, model.Database.Identifier(), err)
 	}
 	return nil
 }
@@ -210,3 +209,35 @@ func (t *TaskRunnerImpl) RemoveFromGroup(model *redshift.MembershipModel) error
 	}
 	return nil
 }
+
+func (t *TaskRunnerImpl) GrantTableAccess(model *redshift.TableGrantsModel) error {
+	t.log.Info(fmt.Sprintf("GrantTableAccess (%s.%s) %s->%s %v", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.Table.Identifier(), model.Table.Columns))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	err = client.GrantTable(model.GroupName, *model.Table)
+
+	if err != nil {
+		return fmt.Errorf("failed to grant access to table %s for group %s on database %s: %w", model.Table.Identifier(), model.GroupName, model.Database.Identifier(), err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) RevokeTableAccess(model *redshift.TableGrantsModel) error {
+	t.log.Info(fmt.Sprintf("RevokeTableAccess (%s.%s) %s->%s %v", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.Table.Identifier(), model.Table.Columns))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	err = client.RevokeTable(model.GroupName, *model.Table)
+
+	if err != nil {
+		return fmt.Errorf("unable to revoke access to table %s for group %s on database %s: %w", model.Table.Identifier(), model.GroupName, model.Database.Identifier(), err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"time"
+)
+
+// Outcome is how an operation ended.
+type Outcome string
+
+const (
+	Succeeded Outcome = "Succeeded"
+	Failed    Outcome = "Failed"
+	Skipped   Outcome = "Skipped"
+)
+
+//The managed systems an operation can be run against.
+const (
+	Redshift = "redshift"
+	Iam      = "iam"
+	Google   = "google"
+)
+
+// Operation is a single change made, or attempted, in one of the managed systems.
+type Operation struct {
+	System   string //redshift, iam or google
+	Type     string //e.g. CreateUser, PolicyUpdated or RolesUpdated
+	Target   string //the user, group, grant, role or policy that was changed
+	Cluster  string //the redshift cluster, only set for redshift tasks
+	Database string //the redshift database, empty for redshift tasks on users and groups
+	Role     string //the IAM role, only set for IAM changes
+	User     string //the email of the google user, only set for google changes
+	Detail   string //e.g. the privilege granted or the roles assigned
+	Outcome  Outcome
+	Err      error
+	Duration time.Duration
+	Attempts int //the number of times a redshift task was run, including retries
+}
+
+//Returns the message of the error, or an empty string if the operation didn't fail.
+func (o Operation) Error() string {
+	if o.Err == nil {
+		return ""
+	}
+	return o.Err.Error()
+}
+
+//Returns where the operation was run, i.e. the cluster and database, the role or the user.
+func (o Operation) Location() string {
+	switch {
+	case o.Cluster != "" && o.Database != "":
+		return fmt.Sprintf("%s/%s", o.Cluster, o.Database)
+	case o.Cluster != "":
+		return o.Cluster
+	case o.Role != "":
+		return o.Role
+	default:
+		return o.User
+	}
+}
+
+func (o Operation) String() string {
+	return fmt.Sprintf("%s(%s) in %s %s", o.Type, o.Target, o.System, o.Location())
+}
+
+// ApplyReport lists the operations run while applying a model, in the order they finished.
+// It is not safe for concurrent use.
+type ApplyReport struct {
+	Operations []Operation
+}
+
+func New() *ApplyReport {
+	return &ApplyReport{}
+}
+
+func (r *ApplyReport) Add(operation Operation) {
+	r.Operations = append(r.Operations, operation)
+}
+
+//Adds the operations of the other report, which may be nil.
+func (r *ApplyReport) Merge(other *ApplyReport) {
+	if other == nil {
+		return
+	}
+	r.Operations = append(r.Operations, other.Operations...)
+}
+
+func (r *ApplyReport) Count(outcome Outcome) int {
+	result := 0
+	for _, operation := range r.Operations {
+		if operation.Outcome == outcome {
+			result++
+		}
+	}
+	return result
+}
+
+func (r *ApplyReport) Failed() []Operation {
+	var result []Operation
+	for _, operation := range r.Operations {
+		if operation.Outcome == Failed {
+			result = append(result, operation)
+		}
+	}
+	return result
+}
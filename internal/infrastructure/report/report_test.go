@@ -0,0 +1,34 @@
+package report
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ApplyReport(t *testing.T) {
+
+	assert := assert.New(t)
+
+	redshift := New()
+	redshift.Add(Operation{System: Redshift, Type: "CreateUser", Target: "jwr", Cluster: "dev", Outcome: Succeeded, Attempts: 1})
+	redshift.Add(Operation{System: Redshift, Type: "GrantAccess", Target: "bianalyst", Cluster: "dev", Database: "jwr", Outcome: Failed, Err: fmt.Errorf("permission denied"), Attempts: 3})
+	redshift.Add(Operation{System: Redshift, Type: "AddToGroup", Target: "jwr_bianalyst", Cluster: "dev", Outcome: Skipped})
+
+	result := New()
+	result.Merge(redshift)
+	result.Merge(nil)
+	result.Add(Operation{System: Iam, Type: "PolicyCreated", Target: "jwr", Role: "BiAnalyst", Outcome: Succeeded})
+
+	assert.Equal(2, result.Count(Succeeded))
+	assert.Equal(1, result.Count(Failed))
+	assert.Equal(1, result.Count(Skipped))
+
+	failed := result.Failed()
+	assert.Len(failed, 1)
+	assert.Equal("GrantAccess(bianalyst) in redshift dev/jwr", failed[0].String())
+	assert.Equal("permission denied", failed[0].Error())
+	assert.Equal("", result.Operations[0].Error())
+	assert.Equal("BiAnalyst", result.Operations[3].Location())
+}
@@ -1,22 +1,73 @@
 package service
 
 import (
+	"fmt"
 	"github.com/go-logr/logr"
+	googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/metrics"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"time"
 )
 
-type IamEventRecorder struct {
-	logger logr.Logger
+// SubsystemResult describes the outcome of applying the model to one of the managed systems.
+type SubsystemResult struct {
+	Skipped  bool                //true if the subsystem was not applied, either because of dry run or because an earlier subsystem failed
+	Err      error               //the error returned by the subsystem applier, if any
+	Duration time.Duration       //how long it took to apply the subsystem
+	Report   *report.ApplyReport //the operations run against the subsystem, nil if it was skipped
 }
 
-func (e *IamEventRecorder) Handle(eventType iam.ApplyEventType, name string) {
-	e.logger.Info("Event occurred", "eventType", eventType.ToString(), "name", name)
+func (r SubsystemResult) Succeeded() bool {
+	return !r.Skipped && r.Err == nil
 }
 
-func NewIamLogger(logger logr.Logger) *IamEventRecorder {
-	return &IamEventRecorder{logger: logger}
+// ApplyResult is the structured outcome of applying a hubble model to redshift, IAM and google.
+type ApplyResult struct {
+	Redshift         SubsystemResult
+	Iam              SubsystemResult
+	Google           SubsystemResult
+	ManagedUsers     int
+	ManagedRoles     int
+	ManagedDatabases int
+	ManagedGrants    int   //the schemas, external schemas and tables granted to redshift groups
+	Plan             *Plan //the changes that would have been made, only set in dry run mode
+}
+
+func (r *ApplyResult) Succeeded() bool {
+	return r.Redshift.Succeeded() && r.Iam.Succeeded() && r.Google.Succeeded()
+}
+
+//Returns the operations run against all of the subsystems, in the order they were applied.
+func (r *ApplyResult) Report() *report.ApplyReport {
+	result := report.New()
+	result.Merge(r.Redshift.Report)
+	result.Merge(r.Iam.Report)
+	result.Merge(r.Google.Report)
+	return result
+}
+
+// DriftReport lists the differences between the desired state and each of the managed systems.
+type DriftReport struct {
+	Redshift []string //the tasks that would be run to bring the clusters in sync
+	Iam      []string
+	Google   []string
+}
+
+func (r *DriftReport) Count() int {
+	return len(r.Redshift) + len(r.Iam) + len(r.Google)
+}
+
+//ErrPlanChanged is returned by ApplyPlan when the changes to make are no longer the ones that were planned.
+var ErrPlanChanged = fmt.Errorf("the changes to make differ from the planned changes")
+
+// ReportListener is given the report of each subsystem once it has been applied, e.g. to record events or write the audit log.
+type ReportListener interface {
+	Reported(applyReport *report.ApplyReport)
 }
 
 type Applier struct {
@@ -24,56 +75,195 @@ type Applier struct {
 	googleApplier   GoogleApplier
 	redshiftApplier RedshiftApplier
 	iamApplier      *iam.Applier
+	listeners       []ReportListener
 	logger          logr.Logger
 }
 
+//The listeners are optional, they are not told about anything in dry run mode.
 func NewApplier(
 	iamApplier *iam.Applier,
 	googleApplier GoogleApplier,
 	redshiftApplier RedshiftApplier,
-	logger logr.Logger) *Applier {
+	resolver *resolver.Resolver,
+	logger logr.Logger,
+	listeners ...ReportListener) *Applier {
 
 	return &Applier{
-		resolver:        &resolver.Resolver{},
+		resolver:        resolver,
 		redshiftApplier: redshiftApplier,
 		iamApplier:      iamApplier,
 		googleApplier:   googleApplier,
+		listeners:       listeners,
 		logger:          logger,
 	}
 }
 
-func (applier *Applier) Apply(model hubble.Model, dryRun bool) error {
+func countDatabases(model redshiftCore.Model) int {
+	result := 0
+	for _, cluster := range model.Clusters {
+		result += len(cluster.Databases)
+	}
+	return result
+}
+
+func countGrants(model redshiftCore.Model) int {
+	result := 0
+	for _, cluster := range model.Clusters {
+		for _, database := range cluster.Databases {
+			for _, group := range database.Groups {
+				result += len(group.GrantedSchemas) + len(group.GrantedExternalSchemas) + len(group.GrantedTables)
+			}
+		}
+	}
+	return result
+}
+
+//Applies one subsystem, recording how long it took and what it did and passing its report on to the listeners.
+func (applier *Applier) applySubsystem(name string, apply func() (*report.ApplyReport, error)) SubsystemResult {
+	start := time.Now()
+	applyReport, err := apply()
+	duration := time.Since(start)
+
+	metrics.ApplyDuration.WithLabelValues(name).Observe(duration.Seconds())
+	recordOperations(applyReport)
+	if applyReport != nil {
+		for _, listener := range applier.listeners {
+			listener.Reported(applyReport)
+		}
+	}
+
+	return SubsystemResult{Err: err, Duration: duration, Report: applyReport}
+}
+
+func recordManagedObjects(result *ApplyResult) {
+	metrics.Managed.WithLabelValues("users").Set(float64(result.ManagedUsers))
+	metrics.Managed.WithLabelValues("roles").Set(float64(result.ManagedRoles))
+	metrics.Managed.WithLabelValues("databases").Set(float64(result.ManagedDatabases))
+	metrics.Managed.WithLabelValues("grants").Set(float64(result.ManagedGrants))
+}
+
+func newApplyResult(redshiftModel redshiftCore.Model, iamModel iamCore.Model, googleModel googleCore.Model) *ApplyResult {
+	result := &ApplyResult{
+		Redshift:         SubsystemResult{Skipped: true},
+		Iam:              SubsystemResult{Skipped: true},
+		Google:           SubsystemResult{Skipped: true},
+		ManagedUsers:     len(googleModel.Users),
+		ManagedRoles:     len(iamModel.Roles),
+		ManagedDatabases: countDatabases(redshiftModel),
+		ManagedGrants:    countGrants(redshiftModel),
+	}
+	recordManagedObjects(result)
+	return result
+}
+
+func (applier *Applier) Apply(model hubble.Model, dryRun bool) (*ApplyResult, error) {
 
 	applier.logger.Info("Received hubble model")
 
 	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
 
-	applier.logger.Info("Applying redshift model")
-	err := applier.redshiftApplier.Apply(redshiftModel, dryRun)
+	result := newApplyResult(redshiftModel, iamModel, googleModel)
+
+	if dryRun {
+		applier.logger.Info("Dry run, the changes are planned but not applied")
+		plan, err := applier.plan(redshiftModel, iamModel, googleModel)
+		result.Plan = plan
+		return result, err
+	}
+
+	return applier.apply(result, redshiftModel, iamModel, googleModel)
+}
+
+//Applies the hubble model if the changes it makes are still the ones of the plan with the given hash, e.g. the plan that was approved.
+//The changes are planned again right before they are applied, so changes made to redshift, IAM or google since the plan was approved are not applied without approval.
+func (applier *Applier) ApplyPlan(model hubble.Model, hash string) (*ApplyResult, error) {
 
+	applier.logger.Info("Received hubble model", "plan", hash)
+
+	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
+
+	result := newApplyResult(redshiftModel, iamModel, googleModel)
+
+	plan, err := applier.plan(redshiftModel, iamModel, googleModel)
 	if err != nil {
-		return err
+		return result, err
+	}
+	if plan.Hash() != hash {
+		result.Plan = plan
+		return result, fmt.Errorf("%w: planned %s, the changes are now %s", ErrPlanChanged, hash, plan.Hash())
+	}
+
+	return applier.apply(result, redshiftModel, iamModel, googleModel)
+}
+
+func (applier *Applier) apply(result *ApplyResult, redshiftModel redshiftCore.Model, iamModel iamCore.Model, googleModel googleCore.Model) (*ApplyResult, error) {
+
+	applier.logger.Info("Applying redshift model")
+	result.Redshift = applier.applySubsystem("redshift", func() (*report.ApplyReport, error) {
+		return applier.redshiftApplier.Apply(redshiftModel, false)
+	})
+
+	if result.Redshift.Err != nil {
+		return result, result.Redshift.Err
 	}
 
 	applier.logger.Info("Applying IAM model")
-	if !dryRun {
-		err = applier.iamApplier.Apply(iamModel)
+	result.Iam = applier.applySubsystem("iam", func() (*report.ApplyReport, error) {
+		return applier.iamApplier.Apply(iamModel)
+	})
 
-		if err != nil {
-			return err
-		}
+	if result.Iam.Err != nil {
+		return result, result.Iam.Err
 	}
 
 	applier.logger.Info("Applying Google model")
-	if !dryRun {
-		err = applier.googleApplier.Apply(googleModel)
+	result.Google = applier.applySubsystem("google", func() (*report.ApplyReport, error) {
+		return applier.googleApplier.Apply(googleModel)
+	})
 
-		if err != nil {
-			return err
-		}
+	if result.Google.Err != nil {
+		return result, result.Google.Err
+	}
+
+	if result.Succeeded() {
+		metrics.LastSuccessfulApply.SetToCurrentTime()
 	}
 
 	applier.logger.Info("All changes have been applied")
 
-	return nil
+	return result, nil
+}
+
+//Compares the hubble model with the current state of redshift, IAM and google without changing anything.
+func (applier *Applier) DetectDrift(model hubble.Model) (*DriftReport, error) {
+
+	applier.logger.Info("Detecting drift")
+
+	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
+
+	result := &DriftReport{}
+	var err error
+
+	result.Redshift, err = applier.redshiftApplier.DetectDrift(redshiftModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect drift in redshift: %w", err)
+	}
+
+	result.Iam, err = applier.iamApplier.DetectDrift(iamModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect drift in IAM: %w", err)
+	}
+
+	result.Google, err = applier.googleApplier.DetectDrift(googleModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to detect drift in google: %w", err)
+	}
+
+	metrics.Drift.WithLabelValues("redshift").Set(float64(len(result.Redshift)))
+	metrics.Drift.WithLabelValues("iam").Set(float64(len(result.Iam)))
+	metrics.Drift.WithLabelValues("google").Set(float64(len(result.Google)))
+
+	applier.logger.Info("Drift detection finished", "redshift", len(result.Redshift), "iam", len(result.Iam), "google", len(result.Google))
+
+	return result, nil
 }
@@ -3,8 +3,10 @@
 package service
 
 import (
+	"errors"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
@@ -92,7 +94,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	session := iam.LocalStackSessionFactory{}.CreateSession()
 	iamClient := iam.New(session)
-	iamApplier := iam.NewApplier(iamClient, accountId, region, &IamEventRecorder{logger: logger}, logger)
+	iamApplier := iam.NewApplier(iamClient, accountId, region, logger)
 
 	redshiftExpected := redshift.NewRedshiftState()
 	redshiftExpected.Users = []string{"lunarway"}
@@ -100,21 +102,21 @@ func TestApplier_Apply(t *testing.T) {
 
 	iamExpected := iam.IAMState{}
 
-	applier := NewApplier(iamApplier, googleApplier, redshiftApplier, logger)
+	applier := NewApplier(iamApplier, googleApplier, redshiftApplier, &resolver.Resolver{}, logger)
 
 	redshiftModel := redshiftCore.Model{}
 	redshiftModel.DeclareCluster("hubble")
-	err := redshiftApplier.Apply(redshiftModel, false)
+	_, err := redshiftApplier.Apply(redshiftModel, false)
 	failOnError(err)
 
 	model := hubble.Model{}
 	user := model.AddUser("jwr", "jwr@lunar.app")
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	log.Info("Create database")
 	database := model.AddDatabase("hubble", "prod")
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftClient, err := redshift.NewClient(
@@ -135,7 +137,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Create role")
 	role := model.AddRole("BiAnalyst", []hubble.DataSet{"public_bi"})
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
@@ -146,7 +148,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Grant role access to database")
 	role.GrantAccess(database)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
@@ -156,7 +158,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Assign user to role")
 	user.Assign(role)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftExpected.Users = []string{"lunarway", "jwr_bianalyst"}
@@ -172,7 +174,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Revoke access")
 	role.RevokeAccess(database)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftExpected = redshift.NewRedshiftState()
@@ -189,11 +191,34 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Unassign user from role")
 	user.Unassign(role)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
 	redshift.AssertState(assert, redshiftActual, redshiftExpected, "the user, group and grants have been removed")
 	iamActual = iam.FetchIAMState(iamClient)
 	iam.AssertState(assert, iamActual, iamExpected, "IAM policy for jwr is still detached from role")
+
+	log.Info("Apply a plan that is no longer up to date")
+	plan, err := applier.Plan(model)
+	failOnError(err)
+	user.Assign(role)
+	role.GrantAccess(database)
+	_, err = applier.ApplyPlan(model, plan.Hash())
+	assert.True(errors.Is(err, ErrPlanChanged))
+
+	redshiftActual = redshift.FetchState(redshiftClient)
+	redshift.AssertState(assert, redshiftActual, redshiftExpected, "the changes that were not planned have not been applied")
+
+	plan, err = applier.Plan(model)
+	failOnError(err)
+	_, err = applier.ApplyPlan(model, plan.Hash())
+	failOnError(err)
+
+	redshiftExpected.Users = []string{"lunarway", "jwr_bianalyst"}
+	redshiftExpected.Groups = []string{"bianalyst"}
+	redshiftExpected.GroupMemberships = map[string][]string{"lunarway": {}, "jwr_bianalyst": {"bianalyst"}}
+	redshiftExpected.Grants = map[string][]string{"bianalyst": {"public", "public_bi"}}
+	redshiftActual = redshift.FetchState(redshiftClient)
+	redshift.AssertState(assert, redshiftActual, redshiftExpected, "the changes of the current plan have been applied")
 }
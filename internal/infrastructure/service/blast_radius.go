@@ -0,0 +1,85 @@
+package service
+
+import (
+	"fmt"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+)
+
+// BlastRadius counts the users, roles and grants a plan removes.
+type BlastRadius struct {
+	DroppedUsers   int //redshift users that are dropped
+	DeletedRoles   int
+	RevokedGrants  int //schemas and tables that redshift groups lose access to
+	RemovedPercent int //the removed users, roles and grants as a percentage of those managed before the plan is applied
+}
+
+// BlastRadiusLimits are the most a single apply may remove. Zero disables a limit.
+type BlastRadiusLimits struct {
+	MaxDroppedUsers   int
+	MaxDeletedRoles   int
+	MaxRevokedGrants  int
+	MaxRemovedPercent int
+}
+
+func (l BlastRadiusLimits) Enabled() bool {
+	return l.MaxDroppedUsers > 0 || l.MaxDeletedRoles > 0 || l.MaxRevokedGrants > 0 || l.MaxRemovedPercent > 0
+}
+
+//Describes the limits the blast radius goes over.
+func (l BlastRadiusLimits) Exceeded(radius BlastRadius) []string {
+	var result []string
+	if l.MaxDroppedUsers > 0 && radius.DroppedUsers > l.MaxDroppedUsers {
+		result = append(result, fmt.Sprintf("%d users are dropped, at most %d are allowed", radius.DroppedUsers, l.MaxDroppedUsers))
+	}
+	if l.MaxDeletedRoles > 0 && radius.DeletedRoles > l.MaxDeletedRoles {
+		result = append(result, fmt.Sprintf("%d roles are deleted, at most %d are allowed", radius.DeletedRoles, l.MaxDeletedRoles))
+	}
+	if l.MaxRevokedGrants > 0 && radius.RevokedGrants > l.MaxRevokedGrants {
+		result = append(result, fmt.Sprintf("%d grants are revoked, at most %d are allowed", radius.RevokedGrants, l.MaxRevokedGrants))
+	}
+	if l.MaxRemovedPercent > 0 && radius.RemovedPercent > l.MaxRemovedPercent {
+		result = append(result, fmt.Sprintf("%d%% of the managed users, roles and grants are removed, at most %d%% is allowed", radius.RemovedPercent, l.MaxRemovedPercent))
+	}
+	return result
+}
+
+func countUsers(model redshiftCore.Model) int {
+	result := 0
+	for _, cluster := range model.Clusters {
+		result += len(cluster.Users)
+	}
+	return result
+}
+
+//Counts what the plan removes. The objects managed before the plan is applied are the desired ones, plus those that are removed, minus those that are created.
+func blastRadius(plan *Plan, desiredUsers int, desiredRoles int, desiredGrants int) BlastRadius {
+	result := BlastRadius{}
+	created := 0
+
+	for _, task := range plan.Redshift {
+		switch task.Type {
+		case redshiftCore.DropUser:
+			result.DroppedUsers++
+		case redshiftCore.RevokeAccess, redshiftCore.RevokeTableAccess:
+			result.RevokedGrants++
+		case redshiftCore.CreateUser, redshiftCore.GrantAccess, redshiftCore.GrantTableAccess:
+			created++
+		}
+	}
+	for _, change := range plan.Iam {
+		switch change.Type {
+		case iam.RoleDeleted:
+			result.DeletedRoles++
+		case iam.RoleCreated:
+			created++
+		}
+	}
+
+	removed := result.DroppedUsers + result.DeletedRoles + result.RevokedGrants
+	managed := desiredUsers + desiredRoles + desiredGrants + removed - created
+	if managed > 0 {
+		result.RemovedPercent = removed * 100 / managed
+	}
+	return result
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_BlastRadius(t *testing.T) {
+
+	assert := assert.New(t)
+
+	plan := &Plan{
+		Redshift: []redshiftCore.PlannedTask{
+			{Type: redshiftCore.DropUser, Identifier: "jwr_bianalyst"},
+			{Type: redshiftCore.DropUser, Identifier: "nra_bianalyst"},
+			{Type: redshiftCore.RevokeAccess, Identifier: "bianalyst->public"},
+			{Type: redshiftCore.CreateUser, Identifier: "kbr_bianalyst"},
+		},
+		Iam: []iam.Change{
+			{Type: iam.RoleDeleted, Name: "Developer"},
+			{Type: iam.PolicyDeleted, Name: "jwr_developer", Role: "Developer"},
+		},
+	}
+
+	//3 users, 1 role and 1 grant are desired, so 4 removed and 1 created means 8 were managed before the plan is applied
+	radius := blastRadius(plan, 3, 1, 1)
+	assert.Equal(BlastRadius{DroppedUsers: 2, DeletedRoles: 1, RevokedGrants: 1, RemovedPercent: 50}, radius)
+	assert.Equal(BlastRadius{}, blastRadius(&Plan{}, 0, 0, 0), "nothing is removed when nothing is managed")
+}
+
+func Test_BlastRadiusLimits(t *testing.T) {
+
+	assert := assert.New(t)
+
+	radius := BlastRadius{DroppedUsers: 2, DeletedRoles: 1, RevokedGrants: 1, RemovedPercent: 50}
+
+	assert.False(BlastRadiusLimits{}.Enabled())
+	assert.Empty(BlastRadiusLimits{}.Exceeded(radius), "limits are disabled by default")
+
+	limits := BlastRadiusLimits{MaxDroppedUsers: 2, MaxDeletedRoles: 1, MaxRevokedGrants: 1, MaxRemovedPercent: 50}
+	assert.True(limits.Enabled())
+	assert.Empty(limits.Exceeded(radius), "the limits are inclusive")
+
+	limits = BlastRadiusLimits{MaxDroppedUsers: 1, MaxRemovedPercent: 25}
+	assert.Equal([]string{
+		"2 users are dropped, at most 1 are allowed",
+		"50% of the managed users, roles and grants are removed, at most 25% is allowed",
+	}, limits.Exceeded(radius))
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
+
+// Event describes a change made to one of the managed systems, or a change that could not be made.
+type Event struct {
+	Warning bool
+	Reason  string
+	Message string
+}
+
+// EventSink receives the events of the changes made while applying a model.
+type EventSink interface {
+	Event(event Event)
+}
+
+// EventRecorder logs the changes in the reports of the redshift, IAM and google appliers and passes them on to the sink.
+type EventRecorder struct {
+	logger logr.Logger
+	sink   EventSink
+}
+
+//The sink is optional, the events are only logged without it.
+func NewEventRecorder(logger logr.Logger, sink EventSink) *EventRecorder {
+	return &EventRecorder{logger: logger, sink: sink}
+}
+
+func (e *EventRecorder) record(event Event) {
+	if e.sink != nil {
+		e.sink.Event(event)
+	}
+}
+
+//Logs the changes of the report of a subsystem and records an event for each of them.
+//An apply can run thousands of redshift tasks, so only the failed ones get an event of their own and the succeeded ones are summed up in one event.
+func (e *EventRecorder) Reported(applyReport *report.ApplyReport) {
+	if applyReport == nil {
+		return
+	}
+	succeededTasks := make(map[string]int)
+	for _, operation := range applyReport.Operations {
+		switch operation.System {
+		case report.Redshift:
+			e.taskFinished(operation, succeededTasks)
+		case report.Iam:
+			e.iamChanged(operation)
+		case report.Google:
+			e.rolesUpdated(operation)
+		}
+	}
+	e.tasksSucceeded(succeededTasks)
+}
+
+//Failed changes to IAM and google are recorded as the failure of the apply.
+func (e *EventRecorder) iamChanged(operation report.Operation) {
+	if operation.Outcome != report.Succeeded {
+		return
+	}
+	e.logger.Info("Event occurred", "eventType", operation.Type, "name", operation.Target)
+
+	switch operation.Type {
+	case iam.RoleCreated.ToString():
+		e.record(Event{Reason: "RoleCreated", Message: fmt.Sprintf("IAM role %s created", operation.Target)})
+	case iam.RoleUpdated.ToString():
+		e.record(Event{Reason: "RoleUpdated", Message: fmt.Sprintf("IAM role %s updated", operation.Target)})
+	case iam.RoleDeleted.ToString():
+		e.record(Event{Reason: "RoleDeleted", Message: fmt.Sprintf("IAM role %s deleted", operation.Target)})
+	case iam.PolicyCreated.ToString():
+		e.record(Event{Reason: "PolicyCreated", Message: fmt.Sprintf("IAM policy %s created", operation.Target)})
+	case iam.PolicyUpdated.ToString():
+		e.record(Event{Reason: "PolicyUpdated", Message: fmt.Sprintf("IAM policy %s updated", operation.Target)})
+	case iam.PolicyDeleted.ToString():
+		e.record(Event{Reason: "PolicyDeleted", Message: fmt.Sprintf("IAM policy %s deleted", operation.Target)})
+	}
+}
+
+//Succeeded tasks are counted by type in succeeded.
+func (e *EventRecorder) taskFinished(operation report.Operation, succeeded map[string]int) {
+	switch operation.Outcome {
+	case report.Failed:
+		description := fmt.Sprintf("%s(%s)", operation.Type, operation.Target)
+		if operation.Attempts > 1 {
+			description = fmt.Sprintf("%s after %d attempts", description, operation.Attempts)
+		}
+		e.record(Event{Warning: true, Reason: "TaskFailed", Message: fmt.Sprintf("redshift task %s failed: %s", description, operation.Error())})
+	case report.Succeeded:
+		succeeded[operation.Type]++
+	}
+	//skipped tasks are logged by the DAG runner and follow from a failed task
+}
+
+//Records the number of succeeded redshift tasks of each type, e.g. "5 redshift tasks succeeded: 3 AddToGroup, 2 GrantAccess".
+func (e *EventRecorder) tasksSucceeded(succeeded map[string]int) {
+	if len(succeeded) == 0 {
+		return
+	}
+	var types []string
+	total := 0
+	for taskType, count := range succeeded {
+		types = append(types, taskType)
+		total += count
+	}
+	sort.Strings(types)
+
+	var counts []string
+	for _, taskType := range types {
+		counts = append(counts, fmt.Sprintf("%d %s", succeeded[taskType], taskType))
+	}
+	e.record(Event{Reason: "RedshiftTasksSucceeded", Message: fmt.Sprintf("%d redshift tasks succeeded: %s", total, strings.Join(counts, ", "))})
+}
+
+func (e *EventRecorder) rolesUpdated(operation report.Operation) {
+	if operation.Outcome != report.Succeeded {
+		return
+	}
+	e.logger.Info("Google roles updated", "email", operation.Target, "roles", operation.Detail)
+	e.record(Event{Reason: "GoogleRolesUpdated", Message: fmt.Sprintf("google roles of %s set to [%s]", operation.Target, operation.Detail)})
+}
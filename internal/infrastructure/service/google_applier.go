@@ -1,7 +1,13 @@
 package service
 
-import googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+import (
+	googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
 
 type GoogleApplier interface {
-	Apply(model googleCore.Model) error
+	Apply(model googleCore.Model) (*report.ApplyReport, error)
+	DetectDrift(model googleCore.Model) ([]string, error)
+	Plan(model googleCore.Model) ([]google.RoleChange, error)
 }
@@ -0,0 +1,35 @@
+package service
+
+import (
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/metrics"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+)
+
+//The task metrics are labelled with the final state of the task rather than the outcome of the operation.
+var taskStates = map[report.Outcome]string{
+	report.Succeeded: redshiftCore.Success.String(),
+	report.Failed:    redshiftCore.Failed.String(),
+	report.Skipped:   redshiftCore.Skipped.String(),
+}
+
+//Counts the redshift tasks and the IAM changes of the report of a subsystem, which is nil if the subsystem failed before running anything.
+//The metrics are kept apart from the events, so they are recorded whether or not events are.
+func recordOperations(applyReport *report.ApplyReport) {
+	if applyReport == nil {
+		return
+	}
+	for _, operation := range applyReport.Operations {
+		switch operation.System {
+		case report.Redshift:
+			metrics.Tasks.WithLabelValues(operation.Type, taskStates[operation.Outcome]).Inc()
+			if operation.Attempts > 1 {
+				metrics.TaskRetries.WithLabelValues(operation.Type).Add(float64(operation.Attempts - 1))
+			}
+		case report.Iam:
+			if operation.Outcome == report.Succeeded {
+				metrics.IamEvents.WithLabelValues(operation.Type).Inc()
+			}
+		}
+	}
+}
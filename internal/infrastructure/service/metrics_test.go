@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/metrics"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/report"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordOperations(t *testing.T) {
+
+	assert := assert.New(t)
+
+	failed := testutil.ToFloat64(metrics.Tasks.WithLabelValues("RevokeAccess", "Failed"))
+	retries := testutil.ToFloat64(metrics.TaskRetries.WithLabelValues("RevokeAccess"))
+	deleted := testutil.ToFloat64(metrics.IamEvents.WithLabelValues("PolicyDeleted"))
+	created := testutil.ToFloat64(metrics.IamEvents.WithLabelValues("PolicyCreated"))
+
+	applyReport := report.New()
+	applyReport.Add(report.Operation{System: report.Redshift, Type: "RevokeAccess", Outcome: report.Failed, Err: fmt.Errorf("permission denied"), Attempts: 3})
+	applyReport.Add(report.Operation{System: report.Iam, Type: "PolicyDeleted", Outcome: report.Succeeded})
+	applyReport.Add(report.Operation{System: report.Iam, Type: "PolicyCreated", Outcome: report.Failed})
+
+	recordOperations(applyReport)
+	recordOperations(nil)
+
+	assert.Equal(failed+1, testutil.ToFloat64(metrics.Tasks.WithLabelValues("RevokeAccess", "Failed")))
+	assert.Equal(retries+2, testutil.ToFloat64(metrics.TaskRetries.WithLabelValues("RevokeAccess")))
+	assert.Equal(deleted+1, testutil.ToFloat64(metrics.IamEvents.WithLabelValues("PolicyDeleted")))
+	assert.Equal(created, testutil.ToFloat64(metrics.IamEvents.WithLabelValues("PolicyCreated")), "changes that failed are not counted")
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"sort"
+)
+
+// Plan lists the changes that applying a hubble model would make to redshift, IAM and google.
+type Plan struct {
+	Redshift    []redshiftCore.PlannedTask
+	Iam         []iam.Change
+	Google      []google.RoleChange
+	BlastRadius BlastRadius //counts what the plan removes
+}
+
+func (p *Plan) Count() int {
+	return len(p.Redshift) + len(p.Iam) + len(p.Google)
+}
+
+//Describes the changes of the plan that remove users, groups, roles, policies or access.
+func (p *Plan) Destructive() []string {
+	var result []string
+	for _, task := range p.Redshift {
+		if task.Type.Destructive() {
+			result = append(result, fmt.Sprintf("%s(%s)", task.Type.String(), task.Identifier))
+		}
+	}
+	for _, change := range p.Iam {
+		if change.Destructive() {
+			result = append(result, fmt.Sprintf("%s(%s)", change.Type.ToString(), change.Name))
+		}
+	}
+	for _, change := range p.Google {
+		if len(change.Removed) > 0 {
+			result = append(result, fmt.Sprintf("RolesRemoved(%s)", change.Email))
+		}
+	}
+	return result
+}
+
+//Returns a copy of the plan with the changes in a fixed order, as the order they are planned in depends on the order redshift, IAM and google list them in.
+func (p *Plan) sorted() *Plan {
+	result := &Plan{
+		Redshift:    append([]redshiftCore.PlannedTask{}, p.Redshift...),
+		Iam:         append([]iam.Change{}, p.Iam...),
+		Google:      append([]google.RoleChange{}, p.Google...),
+		BlastRadius: p.BlastRadius,
+	}
+	sort.Slice(result.Redshift, func(i, j int) bool {
+		a, b := result.Redshift[i], result.Redshift[j]
+		if a.ClusterIdentifier != b.ClusterIdentifier {
+			return a.ClusterIdentifier < b.ClusterIdentifier
+		}
+		if a.DatabaseName != b.DatabaseName {
+			return a.DatabaseName < b.DatabaseName
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Identifier < b.Identifier
+	})
+	sort.Slice(result.Iam, func(i, j int) bool {
+		a, b := result.Iam[i], result.Iam[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Type < b.Type
+	})
+	sort.Slice(result.Google, func(i, j int) bool {
+		return result.Google[i].Email < result.Google[j].Email
+	})
+	return result
+}
+
+//Identifies the plan, two plans have the same hash if they make exactly the same changes, in whatever order they were planned.
+func (p *Plan) Hash() string {
+	data, err := json.Marshal(p.sorted())
+	if err != nil {
+		panic(err) //the plan only consists of strings, numbers and slices of them
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+//Returns the changes Apply would make to bring redshift, IAM and google in sync with the hubble model, without making them.
+func (applier *Applier) Plan(model hubble.Model) (*Plan, error) {
+
+	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
+
+	return applier.plan(redshiftModel, iamModel, googleModel)
+}
+
+func (applier *Applier) plan(redshiftModel redshiftCore.Model, iamModel iamCore.Model, googleModel googleCore.Model) (*Plan, error) {
+
+	applier.logger.Info("Planning changes")
+
+	result := &Plan{}
+	var err error
+
+	result.Redshift, err = applier.redshiftApplier.Plan(redshiftModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan the changes to redshift: %w", err)
+	}
+
+	result.Iam, err = applier.iamApplier.Plan(iamModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan the changes to IAM: %w", err)
+	}
+
+	result.Google, err = applier.googleApplier.Plan(googleModel)
+	if err != nil {
+		return nil, fmt.Errorf("unable to plan the changes to google: %w", err)
+	}
+
+	result.BlastRadius = blastRadius(result, countUsers(redshiftModel), len(iamModel.Roles), countGrants(redshiftModel))
+
+	applier.logger.Info("Changes planned", "redshift", len(result.Redshift), "iam", len(result.Iam), "google", len(result.Google))
+
+	return result, nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_PlanHash(t *testing.T) {
+
+	assert := assert.New(t)
+
+	plan := &Plan{
+		Redshift: []redshiftCore.PlannedTask{
+			{Type: redshiftCore.DropUser, Identifier: "jwr_bianalyst", ClusterIdentifier: "hubble"},
+			{Type: redshiftCore.RevokeAccess, Identifier: "bianalyst->public", ClusterIdentifier: "hubble", DatabaseName: "prod"},
+		},
+		Iam: []iam.Change{
+			{Type: iam.RoleDeleted, Name: "Developer"},
+			{Type: iam.PolicyDeleted, Name: "jwr_developer", Role: "Developer"},
+		},
+		Google: []google.RoleChange{{Email: "jwr@lunar.app"}, {Email: "nra@lunar.app"}},
+	}
+
+	reordered := &Plan{
+		Redshift: []redshiftCore.PlannedTask{plan.Redshift[1], plan.Redshift[0]},
+		Iam:      []iam.Change{plan.Iam[1], plan.Iam[0]},
+		Google:   []google.RoleChange{plan.Google[1], plan.Google[0]},
+	}
+
+	assert.Equal(plan.Hash(), reordered.Hash(), "the order the changes are planned in doesn't change the hash")
+	assert.Equal(redshiftCore.RevokeAccess, reordered.Redshift[0].Type, "hashing doesn't reorder the plan")
+
+	reordered.Redshift = reordered.Redshift[1:]
+	assert.NotEqual(plan.Hash(), reordered.Hash())
+}
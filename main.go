@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/audit"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/redshift"
@@ -38,7 +39,9 @@ import (
 	"github.com/lunarway/hubble-rbac-controller/controllers"
 	// +kubebuilder:scaffold:imports
 
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
 	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -56,7 +59,19 @@ func init() {
 
 var log = logf.Log.WithName("controller_hubblerbac")
 
-func createApplier(conf configuration.Configuration) (*service.Applier, error) {
+//Returns nil if no audit log has been configured.
+func createAuditLog(conf configuration.Configuration) (*audit.Log, error) {
+	if conf.AuditLog == "" {
+		return nil, nil
+	}
+	sink, err := audit.NewSink(conf.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+	return audit.NewLog(sink, log.WithName("audit"))
+}
+
+func createApplier(conf configuration.Configuration, events *service.EventRecorder, auditLog *audit.Log) (*service.Applier, error) {
 
 	excludedUsers := []string{
 		"produser",
@@ -82,12 +97,21 @@ func createApplier(conf configuration.Configuration) (*service.Applier, error) {
 	clientGroup := redshift.NewClientGroup(&redshiftCredentials)
 
 	//for some reason revoking access to the public schema in Redshift has no effect, so every reconcile would try to revoke access to all public schemas (so we skip it)
-	config := redshiftCore.ReconcilerConfig{RevokeAccessToPublicSchema: false}
+	config := redshiftCore.ReconcilerConfig{
+		RevokeAccessToPublicSchema: false,
+		Workers:                    conf.RedshiftWorkers,
+		MaxConnectionsPerCluster:   conf.MaxClusterConnections,
+		Retries: redshiftCore.RetryPolicy{
+			MaxAttempts:    conf.RedshiftTaskAttempts,
+			InitialBackoff: conf.RedshiftRetryBackoff,
+			MaxBackoff:     conf.RedshiftMaxRetryBackoff,
+		},
+	}
 	redshiftApplier := redshift.NewApplier(clientGroup, redshiftCore.NewExclusions(excludedDatabases, excludedUsers), conf.AwsAccountId, log, config)
 
 	session := iam.AwsSessionFactory{}.CreateSession()
 	iamClient := iam.New(session)
-	iamApplier := iam.NewApplier(iamClient, conf.AwsAccountId, conf.Region, service.NewIamLogger(log), log)
+	iamApplier := iam.NewApplier(iamClient, conf.AwsAccountId, conf.Region, log)
 
 	jsonCredentials, err := ioutil.ReadFile(conf.GoogleCredentials)
 	if err != nil {
@@ -99,7 +123,23 @@ func createApplier(conf configuration.Configuration) (*service.Applier, error) {
 	}
 	googleApplier := google.NewApplier(googleClient)
 
-	applier := service.NewApplier(iamApplier, googleApplier, redshiftApplier, log)
+	modelResolver := &resolver.Resolver{
+		IdentityProvider: iamCore.IdentityProvider{Name: conf.IdentityProvider, DefaultSessionDuration: conf.DefaultSessionDuration},
+		Datalake: iamCore.DatalakeAccess{
+			AccountId:        conf.AwsAccountId,
+			Region:           conf.Region,
+			AthenaWorkgroup:  conf.AthenaWorkgroup,
+			LocationTemplate: conf.DatalakeLocationTemplate,
+		},
+	}
+
+	//the events and the audit log are derived from the reports of the redshift, IAM and google appliers
+	listeners := []service.ReportListener{events}
+	if auditLog != nil {
+		listeners = append(listeners, auditLog)
+	}
+
+	applier := service.NewApplier(iamApplier, googleApplier, redshiftApplier, modelResolver, log, listeners...)
 
 	return applier, nil
 }
@@ -107,10 +147,14 @@ func createApplier(conf configuration.Configuration) (*service.Applier, error) {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhook bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"Enable the validating admission webhook for HubbleRbac resources. "+
+			"Requires the webhook server certificates to be mounted.")
 	flag.Parse()
 
 	devMode := os.Getenv("DEVELOPMENT_MODE") != ""
@@ -134,22 +178,55 @@ func main() {
 		setupLog.Error(err, "unable to load configuration")
 	}
 
-	applier, err := createApplier(conf)
+	eventSink := controllers.NewHubbleRbacEventSink(mgr.GetEventRecorderFor("hubble-rbac-controller"))
+
+	auditLog, err := createAuditLog(conf)
+
+	if err != nil {
+		setupLog.Error(err, "unable to create audit log")
+		os.Exit(1)
+	}
+
+	applier, err := createApplier(conf, service.NewEventRecorder(log, eventSink), auditLog)
 
 	if err != nil {
 		setupLog.Error(err, "unable to create applier")
 	}
 
 	if err = (&controllers.HubbleRbacReconciler{
-		Client:  mgr.GetClient(),
-		Log:     ctrl.Log.WithName("controllers").WithName("HubbleRbac"),
-		Scheme:  mgr.GetScheme(),
-		Applier: applier,
-		DryRun:  conf.DryRun,
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("HubbleRbac"),
+		Scheme:            mgr.GetScheme(),
+		Applier:           applier,
+		DryRun:            conf.DryRun,
+		Paused:            conf.Paused,
+		Approvers:         conf.AccessRequestApprovers,
+		ApprovalsVerified: enableWebhook,
+		ResyncInterval:    conf.ResyncInterval,
+		DetectOnly:        conf.DetectDriftOnly,
+		RequireApproval:   conf.RequireApproval,
+		Limits: service.BlastRadiusLimits{
+			MaxDroppedUsers:   conf.MaxDroppedUsers,
+			MaxDeletedRoles:   conf.MaxDeletedRoles,
+			MaxRevokedGrants:  conf.MaxRevokedGrants,
+			MaxRemovedPercent: conf.MaxRemovedPercent,
+		},
+		Events: eventSink,
+		Audit:  auditLog,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HubbleRbac")
 		os.Exit(1)
 	}
+	if enableWebhook {
+		if err = (&controllers.HubbleRbacValidator{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HubbleRbac")
+			os.Exit(1)
+		}
+		if err = (&controllers.AccessRequestValidator{Approvers: conf.AccessRequestApprovers}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AccessRequest")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type ErrorCollector struct {
@@ -35,6 +37,41 @@ type Configuration struct {
 	Region                    string
 	GoogleAdminPrincipalEmail string
 	DryRun                    bool
+	//Paused stops the controller from applying any changes, it is false if not set
+	Paused bool
+	//Approvers may approve access requests, it is empty if access requests are not used
+	AccessRequestApprovers []string
+	//IdentityProvider is the name of the SAML provider in IAM that users log in through, GoogleApps if not set
+	IdentityProvider string
+	//DefaultSessionDuration is the session duration of roles that do not set their own, 4 hours if not set
+	DefaultSessionDuration time.Duration
+	//AthenaWorkgroup is the workgroup users query the data lake in, primary if not set
+	AthenaWorkgroup string
+	//DatalakeLocationTemplate is the S3 location of glue databases that are not declared in a HubbleRbac, with %s replaced by the name of the glue database.
+	//Roles are not given access to the data in S3 of undeclared glue databases if it is not set.
+	DatalakeLocationTemplate string
+	//ResyncInterval is how often the HubbleRbacs are applied even if they haven't changed, never if not set
+	ResyncInterval time.Duration
+	//DetectDriftOnly reports the differences between the HubbleRbacs and the managed systems without fixing them, it is false if not set
+	DetectDriftOnly bool
+	//RequireApproval holds back destructive changes until their plan has been approved, it is false if not set
+	RequireApproval bool
+	//MaxDroppedUsers, MaxDeletedRoles, MaxRevokedGrants and MaxRemovedPercent limit what a single apply may remove, a limit is disabled if it is not set
+	MaxDroppedUsers   int
+	MaxDeletedRoles   int
+	MaxRevokedGrants  int
+	MaxRemovedPercent int
+	//RedshiftWorkers is the number of redshift tasks run at the same time, 1 if not set
+	RedshiftWorkers int
+	//MaxClusterConnections caps the redshift tasks running against the same cluster, 0 (no cap) if not set
+	MaxClusterConnections int
+	//RedshiftTaskAttempts is the number of times a redshift task is run before it fails, 3 if not set
+	RedshiftTaskAttempts int
+	//RedshiftRetryBackoff is the wait before a failed task is retried, doubled for every retry up to RedshiftMaxRetryBackoff
+	RedshiftRetryBackoff    time.Duration
+	RedshiftMaxRetryBackoff time.Duration
+	//AuditLog is where every change is recorded: stdout, an http(s) URL or the path of a file. Nothing is recorded if it is not set
+	AuditLog string
 }
 
 func loadVariable(name string, errorCollector *ErrorCollector) string {
@@ -60,6 +97,67 @@ func loadBool(name string, errorCollector *ErrorCollector) bool {
 	return result
 }
 
+//Loads a boolean that defaults to false if the variable is not set.
+func loadOptionalBool(name string, errorCollector *ErrorCollector) bool {
+	if _, ok := os.LookupEnv(name); !ok {
+		return false
+	}
+	return loadBool(name, errorCollector)
+}
+
+//Loads a variable that falls back to the given default if it is not set.
+func loadOptionalVariable(name string, defaultValue string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	return value
+}
+
+//Loads a duration such as "1h30m" that falls back to the given default if it is not set.
+func loadOptionalDuration(name string, defaultValue time.Duration, errorCollector *ErrorCollector) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		errorCollector.Register(name)
+		return defaultValue
+	}
+	return result
+}
+
+//Loads a non-negative integer that falls back to the given default if it is not set.
+func loadOptionalInt(name string, defaultValue int, errorCollector *ErrorCollector) int {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		errorCollector.Register(name)
+		return defaultValue
+	}
+	return result
+}
+
+//Loads a comma separated list. The variable is optional, an empty list is returned if it is not set.
+func loadOptionalList(name string) []string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 func LoadConfiguration() (Configuration, error) {
 
 	errorCollector := &ErrorCollector{}
@@ -74,6 +172,25 @@ func LoadConfiguration() (Configuration, error) {
 		GoogleAdminPrincipalEmail: loadVariable("GOOGLE_ADMIN_PRINCIPAL_EMAIL", errorCollector),
 		Region:                    "eu-west-1",
 		DryRun:                    loadBool("DRYRUN", errorCollector),
+		Paused:                    loadOptionalBool("PAUSED", errorCollector),
+		AccessRequestApprovers:    loadOptionalList("ACCESS_REQUEST_APPROVERS"),
+		IdentityProvider:          loadOptionalVariable("IDENTITY_PROVIDER", "GoogleApps"),
+		DefaultSessionDuration:    loadOptionalDuration("DEFAULT_SESSION_DURATION", 4*time.Hour, errorCollector),
+		AthenaWorkgroup:           loadOptionalVariable("ATHENA_WORKGROUP", "primary"),
+		DatalakeLocationTemplate:  loadOptionalVariable("DATALAKE_LOCATION_TEMPLATE", ""),
+		ResyncInterval:            loadOptionalDuration("RESYNC_INTERVAL", 0, errorCollector),
+		DetectDriftOnly:           loadOptionalBool("DETECT_DRIFT_ONLY", errorCollector),
+		RequireApproval:           loadOptionalBool("REQUIRE_APPROVAL", errorCollector),
+		MaxDroppedUsers:           loadOptionalInt("MAX_DROPPED_USERS", 0, errorCollector),
+		MaxDeletedRoles:           loadOptionalInt("MAX_DELETED_ROLES", 0, errorCollector),
+		MaxRevokedGrants:          loadOptionalInt("MAX_REVOKED_GRANTS", 0, errorCollector),
+		MaxRemovedPercent:         loadOptionalInt("MAX_REMOVED_PERCENT", 0, errorCollector),
+		RedshiftWorkers:           loadOptionalInt("REDSHIFT_WORKERS", 1, errorCollector),
+		MaxClusterConnections:     loadOptionalInt("REDSHIFT_MAX_CONNECTIONS_PER_CLUSTER", 0, errorCollector),
+		RedshiftTaskAttempts:      loadOptionalInt("REDSHIFT_TASK_ATTEMPTS", 3, errorCollector),
+		RedshiftRetryBackoff:      loadOptionalDuration("REDSHIFT_RETRY_BACKOFF", time.Second, errorCollector),
+		RedshiftMaxRetryBackoff:   loadOptionalDuration("REDSHIFT_MAX_RETRY_BACKOFF", 30*time.Second, errorCollector),
+		AuditLog:                  loadOptionalVariable("AUDIT_LOG", ""),
 	}
 
 	return result, errorCollector.Error()